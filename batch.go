@@ -0,0 +1,374 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"utfcoder/codec"
+	"utfcoder/logger"
+	"utfcoder/types"
+)
+
+// what batch did with a file
+const (
+	batchConverted    = "converted"
+	batchWouldConvert = "would convert"
+	batchUnchanged    = "unchanged"
+	batchSkipped      = "skipped"
+	batchFailed       = "failed"
+)
+
+// globs is a flag which can be given more than once
+type globs []string
+
+func (g *globs) String() string { return strings.Join(*g, ",") }
+func (g *globs) Set(value string) error {
+	if _, err := filepath.Match(value, ""); err != nil {
+		return fmt.Errorf("invalid glob %v", value)
+	}
+	*g = append(*g, value)
+	return nil
+}
+
+// returns whether the name or the slash separated relative path of a file matches any of the globs
+func (g globs) match(relativePath string) bool {
+	relativePath = filepath.ToSlash(relativePath)
+	for _, glob := range g {
+		if ok, _ := filepath.Match(glob, pathBase(relativePath)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, relativePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func pathBase(slashPath string) string {
+	return slashPath[strings.LastIndex(slashPath, "/")+1:]
+}
+
+// batchJob is a file batch converts, relativePath is where it goes below the output directory
+type batchJob struct {
+	path, relativePath string
+}
+
+type batchResult struct {
+	status string
+	// the encoding the file was decoded from, with " with bom" when it had one
+	encoding string
+	detail   string
+}
+
+// batch holds the flags of the batch command besides those of how files are converted
+type batch struct {
+	cfg              config
+	outputDir        string
+	isDryRun         bool
+	include, exclude globs
+	workers          int
+	journalPath      string
+	journal          *journal
+}
+
+// returns the files below the paths, in the order given and walked, which pass the globs
+func (b *batch) collect(paths []string) ([]batchJob, error) {
+	var jobs []batchJob
+
+	// the output directory may be inside a walked directory, converting what a previous run wrote again
+	outputDir := ""
+	if len(b.outputDir) != 0 {
+		outputDir, _ = filepath.Abs(b.outputDir)
+	}
+	journalPath := ""
+	if b.journal != nil {
+		journalPath = b.journal.path
+	}
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			jobs = append(jobs, batchJob{path: root, relativePath: filepath.Base(root)})
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			relativePath, _ := filepath.Rel(root, path)
+			if entry.IsDir() {
+				absolutePath, _ := filepath.Abs(path)
+				if path != root && (b.exclude.match(relativePath) || absolutePath == outputDir) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !entry.Type().IsRegular() || b.exclude.match(relativePath) {
+				return nil
+			}
+			// backups of an earlier run are the originals, not files to convert
+			if len(b.cfg.backupSuffix) != 0 && strings.HasSuffix(path, b.cfg.backupSuffix) {
+				return nil
+			}
+			if absolutePath, _ := filepath.Abs(path); absolutePath == journalPath {
+				return nil
+			}
+			if len(b.include) != 0 && !b.include.match(relativePath) {
+				return nil
+			}
+
+			jobs = append(jobs, batchJob{path: path, relativePath: relativePath})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return jobs, nil
+}
+
+// returns where the converted file goes
+func (b *batch) targetPath(job batchJob) string {
+	if b.cfg.isInPlace {
+		return job.path
+	}
+	return filepath.Join(b.outputDir, job.relativePath)
+}
+
+// converts one file
+func (b *batch) convert(job batchJob) batchResult {
+	data, err := os.ReadFile(job.path)
+	if err != nil {
+		return batchResult{status: batchFailed, detail: err.Error()}
+	}
+
+	// a file an interrupted run already converted is not converted again
+	if b.journal != nil && b.isConverted(job, data) {
+		return batchResult{status: batchUnchanged, detail: "converted by an earlier run"}
+	}
+
+	if codec.IsBinary(data) {
+		return batchResult{status: batchSkipped, detail: "binary"}
+	}
+
+	options := b.cfg.options()
+	options.MaxBytes = b.cfg.maxBytes
+
+	encoding, hasBOM := b.cfg.fromEncoding, false
+	if encoding == types.AUTO {
+		detection := codec.Detect(data)
+		if len(detection.Encoding) == 0 {
+			return batchResult{status: batchSkipped, detail: "unknown encoding, " + detection.Reason}
+		}
+		encoding, hasBOM = detection.Encoding, detection.HasBOM
+	}
+
+	result := batchResult{encoding: encoding}
+	if hasBOM {
+		result.encoding += " with bom"
+	}
+
+	// running again over converted files leaves them be, the output directory gets a copy to mirror the whole tree
+	if encoding == b.cfg.toEncoding && hasBOM == b.cfg.addBOM && !b.cfg.hasTextStage() {
+		result.status, result.detail = batchUnchanged, "already "+result.encoding
+		if !b.isDryRun && !b.cfg.isInPlace {
+			if err := b.write(job, result.encoding, data, data); err != nil {
+				result.status, result.detail = batchFailed, err.Error()
+			}
+		}
+		return result
+	}
+
+	output, report, err := codec.Convert(data, encoding, b.cfg.toEncoding, options)
+	if err != nil {
+		result.status, result.detail = batchFailed, err.Error()
+		return result
+	}
+
+	result.status, result.detail = batchConverted, result.encoding+" -> "+b.cfg.toEncoding
+	if b.isDryRun {
+		result.status = batchWouldConvert
+	}
+	if len(report.Malformed) != 0 {
+		result.detail += fmt.Sprintf(", %v malformed sequences", len(report.Malformed))
+	}
+	if b.isDryRun {
+		return result
+	}
+
+	if err := b.write(job, result.encoding, data, output); err != nil {
+		result.status, result.detail = batchFailed, err.Error()
+	}
+	return result
+}
+
+// returns whether the target of job holds what the journal says an earlier run converted it to
+func (b *batch) isConverted(job batchJob, data []byte) bool {
+	targetPath, _ := filepath.Abs(b.targetPath(job))
+	if !b.cfg.isInPlace {
+		var err error
+		if data, err = os.ReadFile(targetPath); err != nil {
+			return false
+		}
+	}
+	return b.journal.isConverted(targetPath, data)
+}
+
+// writes output where the file of job goes, with the mode and modification time of the source file. data is the
+// source, kept as the backup of in place conversion, encoding is what it was decoded from
+func (b *batch) write(job batchJob, encoding string, data []byte, output []byte) error {
+	targetPath := b.targetPath(job)
+
+	backupPath := ""
+	if b.cfg.isInPlace && len(b.cfg.backupSuffix) != 0 {
+		backupPath = job.path + b.cfg.backupSuffix
+		if err := writeFileAtomic(backupPath, data, job.path); err != nil {
+			return err
+		}
+	}
+
+	if b.journal != nil {
+		entry := journalEntry{Encoding: encoding, Checksum: checksum(data), Converted: checksum(output)}
+		entry.Path, _ = filepath.Abs(job.path)
+		entry.Target, _ = filepath.Abs(targetPath)
+		if len(backupPath) != 0 {
+			entry.Backup, _ = filepath.Abs(backupPath)
+		}
+		if err := b.journal.record(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(targetPath, output, job.path); err != nil {
+		return err
+	}
+
+	logger.Log("Written", len(output), "bytes to", targetPath)
+	return nil
+}
+
+// converts the jobs on b.workers goroutines, the results are in the order of the jobs
+func (b *batch) run(jobs []batchJob) []batchResult {
+	results := make([]batchResult, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(b.workers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				results[idx] = b.convert(jobs[idx])
+			}
+		}()
+	}
+
+	for idx := range jobs {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+func (b *batch) register(fs *flag.FlagSet) {
+	b.cfg.registerConversion(fs)
+	fs.StringVar(&b.outputDir, "o", "", "output `directory` the converted files are written to, mirroring the source tree")
+	b.cfg.registerInPlace(fs)
+	fs.BoolVar(&b.isDryRun, "dry-run", false, "only lists what would be converted and the detected source encodings")
+	fs.Var(&b.include, "include", "only converts files whose name or relative path matches `glob`, may be repeated")
+	fs.Var(&b.exclude, "exclude", "skips files and directories whose name or relative path matches `glob`, may be repeated")
+	fs.IntVar(&b.workers, "j", runtime.NumCPU(), "converts `N` files at once")
+	fs.StringVar(&b.journalPath, "journal", "", "records every file converted in journal `file`, which resumes an interrupted run and which revert restores the originals from")
+}
+
+func (b *batch) check() error {
+	if b.cfg.isInPlace && len(b.outputDir) != 0 {
+		return errors.New("both an output directory and in place conversion provided. use either '-o directory' or '-in-place'")
+	}
+	if !b.cfg.isInPlace && len(b.outputDir) == 0 && !b.isDryRun {
+		return errors.New("no output provided. use '-o directory' or '-in-place', or '-dry-run' to only list the files")
+	}
+	if len(b.journalPath) != 0 && b.cfg.isInPlace && len(b.cfg.backupSuffix) == 0 {
+		return errors.New("a journal restores files converted in place from their backups. use '-backup suffix' with '-journal'")
+	}
+	if b.workers < 1 {
+		return errors.New("invalid number of workers provided. use '-j N' with N of at least 1")
+	}
+	return nil
+}
+
+func runBatch(c *cli, args []string) int {
+	b := batch{cfg: config{fromEncoding: types.AUTO, isBatch: true}}
+
+	fs := c.newFlagSet("batch")
+	b.register(fs)
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		return c.fail(exitUsage, "no file or directory mentioned. use 'utfcoder batch -to encoding -o directory path...'")
+	}
+	if err := b.cfg.resolve(fs); err != nil {
+		return c.fail(exitUsage, err)
+	}
+	if err := RunPrechecks(&b.cfg); err != nil {
+		return c.fail(exitUsage, err)
+	}
+	if err := b.check(); err != nil {
+		return c.fail(exitUsage, err)
+	}
+
+	if len(b.journalPath) != 0 {
+		var err error
+		if b.journal, err = openJournal(b.journalPath, b.isDryRun); err != nil {
+			return c.fail(exitFailure, err)
+		}
+		defer b.journal.close()
+	}
+
+	jobs, err := b.collect(fs.Args())
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	results := b.run(jobs)
+
+	counts := map[string]int{}
+	for idx, result := range results {
+		fmt.Fprintf(c.stdout, "%v: %v (%v)\n", jobs[idx].path, result.status, result.detail)
+		counts[result.status] += 1
+	}
+
+	var summary []string
+	for _, status := range []string{batchConverted, batchWouldConvert, batchUnchanged, batchSkipped, batchFailed} {
+		if count := counts[status]; count > 0 || status == batchConverted && !b.isDryRun {
+			summary = append(summary, fmt.Sprint(count, " ", status))
+		}
+	}
+	fmt.Fprintf(c.stdout, "%v files: %v\n", len(jobs), strings.Join(summary, ", "))
+
+	if counts[batchFailed] > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"utfcoder/codec"
+	"utfcoder/logger"
+	"utfcoder/transform"
+	"utfcoder/types"
+)
+
+// returns the path and encoding of a cat argument, path:encoding names the encoding of its file. the part after the
+// last colon only counts when it is a source encoding, so paths with colons still work
+func catArgument(arg string, fromEncoding string) (string, string) {
+	if idx := strings.LastIndex(arg, ":"); idx > 0 {
+		if encoding := strings.ToLower(arg[idx+1:]); encoding == types.AUTO || isValidSourceEncoding(encoding) {
+			return arg[:idx], encoding
+		}
+	}
+	return arg, fromEncoding
+}
+
+// returns the code points of the line end style, LF for keep
+func lineEndingOf(style types.LineEnding) []uint32 {
+	codepoints, _ := transform.ConvertLineEndings([]uint32{'\n'}, style)
+	return codepoints
+}
+
+func runCat(c *cli, args []string) int {
+	var fromEncoding, toEncoding, targetFile string
+	var lineEnding types.LineEnding
+	var errorMode types.ErrorMode
+	var addBOM bool
+
+	fs := c.newFlagSet("cat")
+	lowerStringVar(fs, &fromEncoding, "from", types.AUTO, "`encoding` of the files which don't name theirs as file:encoding, auto detects it")
+	lowerStringVar(fs, &toEncoding, "to", "", "target `encoding`")
+	fs.StringVar(&targetFile, "t", "", "target `file` to write, stdout when empty")
+	fs.BoolVar(&addBOM, "bom", false, "starts the output with a byte order mark")
+	lowerStringVar(fs, &errorMode, "errors", types.REPLACE, "how undecodable bytes are handled, `mode` is one of replace/surrogateescape")
+	lowerStringVar(fs, &lineEnding, "eol", "", "rewrites every line end to `style` and ends every file joined with one, one of lf/crlf/cr, keep only adds the LF missing at the joins")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		return c.fail(exitUsage, "no file mentioned. use 'utfcoder cat -to encoding file[:encoding]...', - reads stdin")
+	}
+	if !isValidTargetEncoding(toEncoding) {
+		return c.fail(exitUsage, "invalid target encoding provided. use '-to encoding', 'utfcoder list' lists them")
+	}
+	if fromEncoding != types.AUTO && !isValidSourceEncoding(fromEncoding) {
+		return c.fail(exitUsage, "invalid source encoding provided. use '-from encoding', 'utfcoder list' lists them")
+	}
+	if !isValidErrorMode(errorMode) {
+		return c.fail(exitUsage, "invalid error mode provided. use '-errors replace' or '-errors surrogateescape'")
+	}
+	if len(lineEnding) != 0 && !isValidLineEnding(lineEnding) {
+		return c.fail(exitUsage, "invalid line ending provided. use one of lf/crlf/cr/keep")
+	}
+
+	options := codec.Options{ErrorMode: errorMode, EOL: lineEnding}
+	var output []byte
+	for idx, arg := range fs.Args() {
+		path, encoding := catArgument(arg, fromEncoding)
+		// an atomic write would keep the source until the end, but the file it was read from would be gone
+		if len(targetFile) != 0 && path != "-" && isSameFile(path, targetFile) {
+			return c.fail(exitUsage, "target file", targetFile, "is one of the files joined. use '-t' with another file")
+		}
+
+		data, err := c.readSource(path)
+		if err != nil {
+			return c.fail(exitFailure, err)
+		}
+
+		// the decoders drop the byte order mark of every file
+		codepoints, report, encoding, err := decodeSource(data, encoding, options)
+		if err != nil {
+			return c.fail(exitFailure, path+":", err)
+		}
+		logger.Log("Joining", path, "as", encoding)
+		for _, malformed := range report.Malformed {
+			fmt.Fprintf(c.stderr, "%v: offset %v (line %v, column %v): %v %q\n", path, malformed.Offset, malformed.Line, malformed.Column, malformed.Reason, malformed.Bytes)
+		}
+
+		codepoints = codec.Apply(codepoints, options, &report)
+		// the last line of a file would run into the first line of the next one
+		if len(lineEnding) != 0 && idx < fs.NArg()-1 && len(codepoints) != 0 && !transform.IsLineEnding(codepoints[len(codepoints)-1]) {
+			codepoints = append(codepoints, lineEndingOf(lineEnding)...)
+		}
+
+		encodeOptions := options
+		encodeOptions.AddBOM = addBOM && idx == 0
+		encoded, err := codec.Encode(codepoints, toEncoding, encodeOptions)
+		if err != nil {
+			return c.fail(exitFailure, err)
+		}
+		output = append(output, encoded...)
+	}
+
+	if len(targetFile) == 0 {
+		if _, err := c.stdout.Write(output); err != nil {
+			return c.fail(exitFailure, err)
+		}
+		return exitOK
+	}
+	if err := writeFileAtomic(targetFile, output, ""); err != nil {
+		return c.fail(exitFailure, err)
+	}
+	return exitOK
+}
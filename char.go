@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"utfcoder/codec"
+	"utfcoder/escape"
+	"utfcoder/types"
+	"utfcoder/ucd"
+	"utfcoder/utils"
+)
+
+// encodings char shows every code point in, with and without byte order mark
+var charEncodings = [5]string{types.UTF_8, types.UTF_16LE, types.UTF_16BE, types.UTF_32LE, types.UTF_32BE}
+
+// the bit layout of every utf-8 sequence length, x marks the bits of the code point
+var utf8Layouts = [4]string{"0xxxxxxx", "110xxxxx 10xxxxxx", "1110xxxx 10xxxxxx 10xxxxxx", "11110xxx 10xxxxxx 10xxxxxx 10xxxxxx"}
+
+func isHexDigits(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// returns whether arg is a hex byte string: 0x or \x prefixed, or pairs of hex digits separated by spaces like "E2 80 99"
+func isByteString(arg string) bool {
+	if strings.HasPrefix(arg, "0x") || strings.HasPrefix(arg, `\x`) {
+		return true
+	}
+
+	fields := strings.Fields(arg)
+	if len(fields) < 2 {
+		return false
+	}
+	for _, field := range fields {
+		if len(field) != 2 || !isHexDigits(field) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseByteString(arg string) ([]byte, error) {
+	digits := strings.NewReplacer("0x", "", `\x`, "", " ", "", ",", "", ":", "").Replace(arg)
+	bytes, err := hex.DecodeString(digits)
+	if err != nil || len(bytes) == 0 {
+		return nil, errors.New("invalid byte string " + arg + ", expected hex bytes like 'E2 80 99'")
+	}
+	return bytes, nil
+}
+
+// returns the code points of U+XXXX notations separated by spaces, false if arg isn't one
+func parseCodepointNotations(arg string) ([]uint32, bool) {
+	var codepoints []uint32
+
+	for _, field := range strings.Fields(arg) {
+		if len(field) < 3 || (field[0] != 'U' && field[0] != 'u') || field[1] != '+' {
+			return nil, false
+		}
+		bits, err := strconv.ParseUint(field[2:], 16, 32)
+		if err != nil {
+			return nil, false
+		}
+		codepoints = append(codepoints, uint32(bits))
+	}
+	return codepoints, len(codepoints) != 0
+}
+
+// returns whether arg could be a unicode name, which is upper case letters, digits, spaces and hyphens
+func isNameLike(arg string) bool {
+	if len(arg) < 2 {
+		return false
+	}
+	for _, r := range arg {
+		if !(r >= 'A' && r <= 'Z') && !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != ' ' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// groups the bits of value, most significant first, into fields of the widths given
+func bitFields(value uint32, widths ...int) string {
+	total := 0
+	for _, width := range widths {
+		total += width
+	}
+
+	var fields []string
+	for _, width := range widths {
+		total -= width
+		fields = append(fields, fmt.Sprintf("%0*b", width, (value>>total)&(1<<width-1)))
+	}
+	return strings.Join(fields, " ")
+}
+
+// prints how the utf-8 bytes of bits carry its bits
+func printUTF8Layout(w io.Writer, bits uint32) {
+	encoded := utils.AppendUTF8(nil, bits, types.REPLACE)
+
+	var binary []string
+	for _, b := range encoded {
+		binary = append(binary, fmt.Sprintf("%08b", b))
+	}
+
+	// the payload widths of every sequence length, the lead byte holds the most significant bits
+	payloads := [4][]int{{7}, {5, 6}, {4, 6, 6}, {3, 6, 6, 6}}
+
+	fmt.Fprintf(w, "  utf-8 layout    %v\n", utf8Layouts[len(encoded)-1])
+	fmt.Fprintf(w, "                  %v\n", strings.Join(binary, " "))
+	fmt.Fprintf(w, "  payload bits    %v = 0x%X\n", bitFields(bits, payloads[len(encoded)-1]...), bits)
+}
+
+// prints how bits is split into a utf-16 surrogate pair
+func printSurrogates(w io.Writer, bits uint32) {
+	high, low := utils.SplitSurrogates(bits)
+	offset := bits - 0x10000
+
+	fmt.Fprintf(w, "  surrogates      0x%X - 0x10000 = 0x%05X = %v\n", bits, offset, bitFields(offset, 10, 10))
+	fmt.Fprintf(w, "                  high 0xD800 + %v = %04X, low 0xDC00 + %v = %04X\n", bitFields(offset>>10, 10), high, bitFields(offset, 10), low)
+}
+
+// prints bits in every encoding, or why it has no encoding
+func printCodepoint(w io.Writer, bits uint32) {
+	if bits > 0x10FFFF {
+		fmt.Fprintf(w, "U+%04X  beyond U+10FFFF, invalid in every encoding\n\n", bits)
+		return
+	}
+
+	name, category := ucd.Name(bits), ucd.Category(bits)
+	if bits >= 0xD800 && bits <= 0xDFFF {
+		fmt.Fprintf(w, "U+%04X  %v (%v)\n", bits, name, category)
+		fmt.Fprintln(w, "  a surrogate is not a unicode scalar value and is invalid in every encoding. in utf-16 it is only")
+		fmt.Fprintln(w, "  valid as one half of a surrogate pair")
+		fmt.Fprintln(w)
+		return
+	}
+
+	glyph := strconv.QuoteRune(rune(bits))
+	fmt.Fprintf(w, "U+%04X  %v (%v)  %v\n", bits, name, category, glyph)
+
+	for _, encoding := range charEncodings {
+		encoded, _ := codec.Encode([]uint32{bits}, encoding, codec.Options{})
+		withBOM, _ := codec.Encode([]uint32{bits}, encoding, codec.Options{AddBOM: true})
+		fmt.Fprintf(w, "  %-14v  % -11X  with bom % X\n", encoding, encoded, withBOM)
+	}
+
+	for _, flavor := range escape.Flavors {
+		encoded := escape.Encode([]uint32{bits}, flavor, types.NON_PRINTABLE, types.REPLACE)
+		fmt.Fprintf(w, "  %-14v  %s\n", flavor, encoded)
+	}
+	for _, encoding := range []string{types.HTML_ENTITIES, types.XML_CHARREF} {
+		fmt.Fprintf(w, "  %-14v  %s\n", encoding, escape.EncodeReferences([]uint32{bits}, encoding, types.ASCII, types.REPLACE))
+	}
+
+	printUTF8Layout(w, bits)
+	if bits >= 0x10000 {
+		printSurrogates(w, bits)
+	} else {
+		fmt.Fprintf(w, "  surrogates      none, U+%04X is a single utf-16 code unit\n", bits)
+	}
+	fmt.Fprintln(w)
+}
+
+// prints what bytes decode to in every encoding
+func printByteString(w io.Writer, bytes []byte) []uint32 {
+	var utf8Codepoints []uint32
+
+	fmt.Fprintf(w, "bytes % X\n", bytes)
+	for _, encoding := range charEncodings {
+		codepoints, report, _ := codec.Decode(bytes, encoding, codec.Options{ErrorMode: types.SURROGATE_ESCAPE})
+
+		var decoded []string
+		for _, bits := range codepoints {
+			if utils.IsEscapedByte(bits) {
+				decoded = append(decoded, fmt.Sprintf("<%02X>", byte(bits)))
+			} else {
+				decoded = append(decoded, fmt.Sprintf("U+%04X", bits))
+			}
+		}
+
+		if len(report.Malformed) == 0 {
+			fmt.Fprintf(w, "  %-14v  valid    %v\n", encoding, strings.Join(decoded, " "))
+		} else {
+			fmt.Fprintf(w, "  %-14v  invalid  %v (%v at offset %v)\n", encoding, strings.Join(decoded, " "), report.Malformed[0].Reason, report.Malformed[0].Offset)
+		}
+
+		if encoding == types.UTF_8 && len(report.Malformed) == 0 {
+			utf8Codepoints = codepoints
+		}
+	}
+	fmt.Fprintln(w)
+
+	return utf8Codepoints
+}
+
+func runChar(c *cli, args []string) int {
+	var isBytes bool
+
+	fs := c.newFlagSet("char")
+	fs.BoolVar(&isBytes, "bytes", false, "reads every argument as hex bytes, even when it doesn't look like them")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		return c.fail(exitUsage, "no character mentioned. use 'utfcoder char ’', 'utfcoder char U+2019', 'utfcoder char \"RIGHT SINGLE QUOTATION MARK\"' or 'utfcoder char \"E2 80 99\"'")
+	}
+
+	for _, arg := range fs.Args() {
+		var codepoints []uint32
+
+		if isBytes || isByteString(arg) {
+			bytes, err := parseByteString(arg)
+			if err != nil {
+				return c.fail(exitUsage, err)
+			}
+			// the code points are shown when the bytes are utf-8, which is what people paste
+			codepoints = printByteString(c.stdout, bytes)
+		} else if notations, ok := parseCodepointNotations(arg); ok {
+			codepoints = notations
+		} else if bits, ok := ucd.Lookup(arg); ok && isNameLike(arg) {
+			codepoints = []uint32{bits}
+		} else {
+			for _, r := range arg {
+				codepoints = append(codepoints, uint32(r))
+			}
+		}
+
+		for _, bits := range codepoints {
+			printCodepoint(c.stdout, bits)
+		}
+	}
+
+	return exitOK
+}
@@ -0,0 +1,53 @@
+package charset
+
+import "utfcoder/types"
+
+// windows-1252 characters of the bytes 0x80-0x9F. the bytes 1252 leaves undefined (0x81, 0x8D, 0x8F, 0x90, 0x9D)
+// are read as the C1 control of the same value, like browsers do
+var windows1252HighControls = [32]uint32{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+var windows1252Bytes = map[uint32]byte{}
+
+func init() {
+	for idx, bits := range windows1252HighControls {
+		windows1252Bytes[bits] = byte(0x80 + idx)
+	}
+}
+
+// returns the code point of byte b in a single byte charset
+func DecodeByte(b byte, charset string) uint32 {
+	if charset == types.WINDOWS_1252 && b >= 0x80 && b <= 0x9F {
+		return windows1252HighControls[b-0x80]
+	}
+	return uint32(b)
+}
+
+// returns the byte of code point bits in a single byte charset, false if the charset doesn't have it
+func EncodeCodepoint(bits uint32, charset string) (byte, bool) {
+	if charset == types.WINDOWS_1252 {
+		if b, ok := windows1252Bytes[bits]; ok {
+			return b, true
+		}
+		if bits >= 0x80 && bits <= 0x9F {
+			return 0, false
+		}
+	}
+
+	if bits > 0xFF || (charset == types.ASCII && bits > 0x7F) {
+		return 0, false
+	}
+	return byte(bits), true
+}
+
+func Decode(input []byte, charset string) []uint32 {
+	var codepoints = make([]uint32, 0, len(input))
+
+	for _, b := range input {
+		codepoints = append(codepoints, DecodeByte(b, charset))
+	}
+
+	return codepoints
+}
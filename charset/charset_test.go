@@ -0,0 +1,95 @@
+package charset
+
+import (
+	"slices"
+	"testing"
+	"utfcoder/types"
+)
+
+func TestDecodeWindows1252(t *testing.T) {
+	input := []byte{'a', 0x80, 0x81, 0x93, 0xE9}
+	expected := []uint32{'a', 0x20AC, 0x81, 0x201C, 0xE9}
+
+	if output := Decode(input, types.WINDOWS_1252); !slices.Equal(output, expected) {
+		t.Errorf(`Decode(%v, windows-1252) = output=%v, Expected = output=%v`, input, output, expected)
+	}
+}
+
+func TestEncodeCodepoint(t *testing.T) {
+	for _, test := range encodeCodepointTestInputs {
+		output, ok := EncodeCodepoint(test.bits, test.charset)
+
+		if output != test.expected || ok != test.ok {
+			t.Errorf(`EncodeCodepoint(%X, %v) = output=%X, ok=%v, Expected = output=%X, ok=%v`, test.bits, test.charset, output, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestDecodeShiftJIS(t *testing.T) {
+	for _, test := range decodeShiftJISTestInputs {
+		output, escaped := DecodeShiftJIS(test.input, test.errorMode)
+
+		if !slices.Equal(output, test.expected) || escaped != test.escaped {
+			t.Errorf(`DecodeShiftJIS(%X, %v) = output=%X, escaped=%v, Expected = output=%X, escaped=%v`, test.input, test.errorMode, output, escaped, test.expected, test.escaped)
+		}
+	}
+}
+
+func TestDecodeMixed(t *testing.T) {
+	for _, test := range decodeMixedTestInputs {
+		output, _, lines := DecodeMixed(test.input, test.fallback, types.REPLACE)
+
+		if !slices.Equal(output, test.expected) || !slices.Equal(lines, test.lines) {
+			t.Errorf(`DecodeMixed(%v, %v) = output=%v, lines=%v, Expected = output=%v, lines=%v`, test.input, test.fallback, output, lines, test.expected, test.lines)
+		}
+	}
+}
+
+var encodeCodepointTestInputs = []struct {
+	bits     uint32
+	charset  string
+	expected byte
+	ok       bool
+}{
+	{0x20AC, types.WINDOWS_1252, 0x80, true},
+	{0x81, types.WINDOWS_1252, 0x81, true},
+	{0x80, types.WINDOWS_1252, 0x00, false},
+	{0x80, types.LATIN_1, 0x80, true},
+	{0x20AC, types.LATIN_1, 0x00, false},
+	{0xE9, types.ASCII, 0x00, false},
+}
+
+var decodeShiftJISTestInputs = []struct {
+	input     []byte
+	errorMode types.ErrorMode
+	expected  []uint32
+	escaped   int
+}{
+	// 日本.txt
+	{[]byte{0x93, 0xFA, 0x96, 0x7B, '.', 't', 'x', 't'}, types.REPLACE, []uint32{0x65E5, 0x672C, '.', 't', 'x', 't'}, 0},
+	// halfwidth katakana ｱｲ, and ① of the windows extensions
+	{[]byte{0xB1, 0xB2, 0x87, 0x40}, types.REPLACE, []uint32{0xFF71, 0xFF72, 0x2460}, 0},
+	// a lead byte followed by a byte which can't trail it, and one cut off by the end
+	{[]byte{0x81, ' ', 0x93}, types.REPLACE, []uint32{0xFFFD, ' ', 0xFFFD}, 0},
+	{[]byte{0x81, ' ', 0xFD}, types.SURROGATE_ESCAPE, []uint32{0xDC81, ' ', 0xDCFD}, 2},
+}
+
+var decodeMixedTestInputs = []struct {
+	input    []byte
+	fallback string
+	expected []uint32
+	lines    []int
+}{
+	// "café" in utf-8, then in windows-1252 with curly quotes
+	{[]byte{'c', 'a', 'f', 0xC3, 0xA9, '\n', 0x93, 0xE9, 0x94, '\n'}, types.WINDOWS_1252, []uint32{'c', 'a', 'f', 0xE9, '\n', 0x201C, 0xE9, 0x201D, '\n'}, []int{2}},
+	{[]byte{'a', '\n', 0xE9}, types.LATIN_1, []uint32{'a', '\n', 0xE9}, []int{2}},
+	// a utf-16le line, its LF 0A 00 must not leak a NUL into the next line
+	{[]byte{'o', 'k', '\n', 'h', 0x00, 'i', 0x00, '\n', 0x00, 'o', 'k', '\n'}, types.UTF_16LE, []uint32{'o', 'k', '\n', 'h', 'i', '\n', 'o', 'k', '\n'}, []int{2}},
+	{[]byte{0x00, 'h', 0x00, 'i', 0x00, '\n', 'o', 'k'}, types.UTF_16BE, []uint32{'h', 'i', '\n', 'o', 'k'}, []int{1}},
+	// a 0A byte which is half of a code unit doesn't end the line: Ċ (U+010A) in utf-16le, ਕ (U+0A15) in utf-16be
+	{[]byte{'o', 'k', '\n', 'a', 0x00, 0x0A, 0x01, 'b', 0x00, '\n', 0x00, 'o', 'k'}, types.UTF_16LE, []uint32{'o', 'k', '\n', 'a', 0x010A, 'b', '\n', 'o', 'k'}, []int{2}},
+	{[]byte{0x00, 'a', 0x0A, 0x15, 0x00, '\n', 'o', 'k'}, types.UTF_16BE, []uint32{'a', 0x0A15, '\n', 'o', 'k'}, []int{1}},
+	{[]byte{'o', 'k', '\n', 0x83, 0x65, 0x83, 0x58, 0x83, 0x67}, types.SHIFT_JIS, []uint32{'o', 'k', '\n', 0x30C6, 0x30B9, 0x30C8}, []int{2}},
+	// valid utf-8 with a NUL only needs a fallback when the fallback is utf-16
+	{[]byte{'a', 0x00, '\n'}, types.WINDOWS_1252, []uint32{'a', 0x00, '\n'}, nil},
+}
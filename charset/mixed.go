@@ -0,0 +1,80 @@
+package charset
+
+import (
+	"bytes"
+	"unicode/utf8"
+	"utfcoder/types"
+	UTF16 "utfcoder/utf16"
+	UTF8 "utfcoder/utf8"
+)
+
+// returns whether a line has to be decoded with fallback. ascii encoded as utf-16 is valid utf-8,
+// so with a utf-16 fallback a NUL byte gives it away as well
+func needsFallback(line []byte, fallback string) bool {
+	if !utf8.Valid(line) {
+		return true
+	}
+	return (fallback == types.UTF_16LE || fallback == types.UTF_16BE) && bytes.IndexByte(line, 0) >= 0
+}
+
+// returns the end of the line starting at input[start], line end included
+func lineEnd(input []byte, start int) int {
+	end := bytes.IndexByte(input[start:], '\n')
+	if end < 0 {
+		return len(input)
+	}
+	return start + end + 1
+}
+
+// returns the end of the utf-16 line starting at input[start], line end included. only a whole LF unit ends it, a 0A
+// byte may be half of another code unit, like U+010A (0A 01) in utf-16le or U+0A15 (0A 15) in utf-16be
+func unitLineEnd(input []byte, start int, fallback string) int {
+	lf := []byte{'\n', 0x00}
+	if fallback == types.UTF_16BE {
+		lf = []byte{0x00, '\n'}
+	}
+	for i := start; i+1 < len(input); i += 2 {
+		if input[i] == lf[0] && input[i+1] == lf[1] {
+			return i + 2
+		}
+	}
+	return len(input)
+}
+
+// returns input decoded line by line, every line which isn't valid utf-8 is decoded with fallback instead.
+// also returns the number of bytes carried as surrogate escapes and the 1-based numbers of the lines fallback decoded
+func DecodeMixed(input []byte, fallback string, errorMode types.ErrorMode) ([]uint32, int, []int) {
+	var codepoints = make([]uint32, 0, len(input))
+	var escaped int
+	var fallbackLines []int
+
+	line := 1
+	for start := 0; start < len(input); line += 1 {
+		end := lineEnd(input, start)
+		run := input[start:end]
+
+		if !needsFallback(run, fallback) {
+			decoded, count, _ := UTF8.Decode(run, errorMode)
+			codepoints, escaped = append(codepoints, decoded...), escaped+count
+			start = end
+			continue
+		}
+
+		fallbackLines = append(fallbackLines, line)
+		switch fallback {
+		case types.UTF_16LE, types.UTF_16BE:
+			end = unitLineEnd(input, start, fallback)
+			run = input[start:end]
+			decoded, count, _ := UTF16.Decode(run, fallback, errorMode)
+			codepoints, escaped = append(codepoints, decoded...), escaped+count
+		case types.SHIFT_JIS:
+			decoded, count := DecodeShiftJIS(run, errorMode)
+			codepoints, escaped = append(codepoints, decoded...), escaped+count
+		default:
+			codepoints = append(codepoints, Decode(run, fallback)...)
+		}
+		start = end
+	}
+
+	return codepoints, escaped, fallbackLines
+}
@@ -0,0 +1,57 @@
+package charset
+
+import (
+	"unicode/utf8"
+	"utfcoder/types"
+	"utfcoder/utils"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// returns whether b starts a 2 byte sequence of shift_jis
+func isShiftJISLead(b byte) bool {
+	return (b >= 0x81 && b <= 0x9F) || (b >= 0xE0 && b <= 0xFC)
+}
+
+// returns input decoded as shift_jis, with the extensions of windows code page 932, and the number of bytes carried as
+// surrogate escapes. every byte of an undecodable sequence is replaced (or escaped) on its own
+func DecodeShiftJIS(input []byte, errorMode types.ErrorMode) ([]uint32, int) {
+	var codepoints = make([]uint32, 0, len(input))
+	var escaped int
+	decoder := japanese.ShiftJIS.NewDecoder()
+
+	for i := 0; i < len(input); {
+		b := input[i]
+		if b < 0x80 {
+			codepoints = append(codepoints, uint32(b))
+			i += 1
+			continue
+		}
+		// halfwidth katakana take a single byte
+		if b >= 0xA1 && b <= 0xDF {
+			codepoints = append(codepoints, 0xFF61+uint32(b-0xA1))
+			i += 1
+			continue
+		}
+
+		if isShiftJISLead(b) && i+1 < len(input) {
+			// the decoder writes U+FFFD for a pair it has no character for
+			decoded, err := decoder.Bytes(input[i : i+2])
+			if r, size := utf8.DecodeRune(decoded); err == nil && r != utf8.RuneError && size == len(decoded) {
+				codepoints = append(codepoints, uint32(r))
+				i += 2
+				continue
+			}
+		}
+
+		if errorMode == types.SURROGATE_ESCAPE {
+			codepoints = append(codepoints, utils.EscapeByte(b))
+			escaped += 1
+		} else {
+			codepoints = append(codepoints, utils.GenerateUnknownCharacter(types.UTF_32))
+		}
+		i += 1
+	}
+
+	return codepoints, escaped
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"utfcoder/codec"
+	"utfcoder/logger"
+	"utfcoder/types"
+)
+
+// exit codes of every command
+const (
+	exitOK = 0
+	// the input failed the check of the command, like validate finding a malformed sequence
+	exitInvalid = 1
+	// unknown command, invalid flags or missing arguments
+	exitUsage = 2
+	// reading, converting or writing failed
+	exitFailure = 3
+)
+
+// cli is where a run of utfcoder reads and writes, os.Stdin/os.Stdout/os.Stderr outside of tests
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name string
+	// arguments shown after the name in the usage line
+	arguments string
+	summary   string
+	run       func(c *cli, args []string) int
+}
+
+var commands []command
+
+func init() {
+	// commands refers to help, which prints commands, so it is filled in here
+	commands = []command{
+		{"convert", "-s file -from encoding -to encoding [flags]", "converts a file from one encoding to another", runConvert},
+		{"split", "-s file -from encoding -to encoding -max-bytes N [flags]", "converts a file and cuts the output into numbered parts of at most N bytes", runSplit},
+		{"fix-mojibake", "-s file -from encoding [flags]", "converts a file, undoing utf-8 which was misread as windows-1252 or latin-1", runFixMojibake},
+		{"cat", "-to encoding [flags] file[:encoding]...", "joins files of any encodings into one stream of one encoding, with at most one byte order mark", runCat},
+		{"batch", "-to encoding (-o directory | -in-place | -dry-run) [flags] path...", "converts files and directory trees, detecting the encoding of every file", runBatch},
+		{"revert", "[-dry-run] journal", "restores the files a batch run converted, from its journal", runRevert},
+		{"rename", "[-from charset] [-nfc] [-dry-run] path...", "renames files and directory trees whose names are windows-1252 or shift_jis to utf-8", runRename},
+		{"watch", "-to encoding -o directory [flags] directory", "converts the files appearing in a directory as soon as they stop changing", runWatch},
+		{"detect", "file...", "guesses the encoding of files", runDetect},
+		{"validate", "-s file [-from encoding]", "checks that a file is valid in its encoding", runValidate},
+		{"inspect", "-s file [-from encoding]", "prints every code point of a file with its bytes, name and category", runInspect},
+		{"stats", "-s file [-from encoding]", "prints counts of the bytes, code points, lines and line ends of a file", runStats},
+		{"strings", "[-n length] [-e encodings] [-class class] [-script scripts] file...", "prints the utf-8, utf-16 and utf-32 text found in binary files, with its offset and encoding", runStrings},
+		{"char", "character|U+XXXX|name|\"hex bytes\"...", "shows characters or bytes in every encoding, with their utf-8 and utf-16 bit layouts", runChar},
+		{"grep", "[-i] [-ignore-normalization] [flags] pattern path[:encoding]...", "searches files of any encodings for a regular expression, printing the matches as utf-8", runGrep},
+		{"compare", "[-from-a encoding] [-from-b encoding] [flags] file file", "checks that two files hold the same text, whatever their encodings", runCompare},
+		{"gen", "-to encoding [-t file] [-n code points] [-seed N] [flags]", "generates random text in any encoding with chosen defects, and a manifest of the defects", runGen},
+		{"list", "", "lists the supported encodings, charsets and modes", runList},
+		{"help", "[command]", "prints the usage of utfcoder or of a command", runHelp},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// runs utfcoder with args (without the program name) and returns its exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	logger.Init(false, stderr)
+
+	if len(args) == 0 {
+		c.printUsage(stderr)
+		return exitUsage
+	}
+
+	if isHelpFlag(args[0]) {
+		c.printUsage(stdout)
+		return exitOK
+	}
+
+	// without a command the flags are those of convert, as they were before there were commands
+	if strings.HasPrefix(args[0], "-") {
+		return runConvert(c, args)
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q. use 'utfcoder help' to list the commands\n", args[0])
+		return exitUsage
+	}
+	return cmd.run(c, args[1:])
+}
+
+func (c *cli) printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: utfcoder <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-13v %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nuse 'utfcoder help <command>' or 'utfcoder <command> -h' for the flags of a command.")
+	fmt.Fprintln(w, "\nexit codes:")
+	fmt.Fprintln(w, "  0  success")
+	fmt.Fprintln(w, "  1  the input failed the check of the command")
+	fmt.Fprintln(w, "  2  unknown command, invalid flags or missing arguments")
+	fmt.Fprintln(w, "  3  reading, converting or writing failed")
+}
+
+// returns the flag set of a command, printing its usage and flags on -h
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	cmd, _ := findCommand(name)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: utfcoder %v %v\n\n%v\n", cmd.name, cmd.arguments, cmd.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(fs.Output(), "\nflags:")
+			fs.PrintDefaults()
+		}
+	}
+
+	fs.Var(verboseFlag{c}, "verbose", "prints logs for debugging")
+
+	return fs
+}
+
+// verboseFlag is a boolean flag turning on the debugging logs of logger
+type verboseFlag struct{ c *cli }
+
+func (f verboseFlag) String() string   { return "" }
+func (f verboseFlag) IsBoolFlag() bool { return true }
+func (f verboseFlag) Set(value string) error {
+	logger.Init(value == "true", f.c.stderr)
+	return nil
+}
+
+// parses args into fs. returns false and the exit code when the command shouldn't go on
+func (c *cli) parse(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	} else if err != nil {
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+func (c *cli) fail(code int, items ...any) int {
+	fmt.Fprintln(c.stderr, items...)
+	return code
+}
+
+// returns the contents of path, "-" is stdin
+func (c *cli) readSource(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// opens path to read a piece at a time, "-" is stdin
+func (c *cli) openSource(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(c.stdin), nil
+	}
+	return os.Open(path)
+}
+
+// returns the source file of a command which takes it as -s or as its only argument
+func sourceArgument(fs *flag.FlagSet, sourceFile string) (string, error) {
+	if len(sourceFile) != 0 && fs.NArg() == 0 {
+		return sourceFile, nil
+	}
+	if len(sourceFile) == 0 && fs.NArg() == 1 {
+		return fs.Arg(0), nil
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+	return "", errors.New("no source file path mentioned. use '-s filepath/filename' to mention source file path")
+}
+
+// lowerString is a flag stored lower cased, like every encoding and mode name
+type lowerString[T ~string] struct{ value *T }
+
+func (s lowerString[T]) String() string {
+	if s.value == nil {
+		return ""
+	}
+	return string(*s.value)
+}
+
+func (s lowerString[T]) Set(value string) error {
+	*s.value = T(strings.ToLower(value))
+	return nil
+}
+
+func lowerStringVar[T ~string](fs *flag.FlagSet, value *T, name string, defaultValue T, usage string) {
+	*value = defaultValue
+	fs.Var(lowerString[T]{value}, name, usage)
+}
+
+// returns the code points of a source, decoding it from fromEncoding or, when that is auto, from the detected encoding.
+// input which isn't utf-16 or utf-32 by its looks is decoded as utf-8, so its malformed sequences can be reported
+func decodeSource(data []byte, fromEncoding string, options codec.Options) ([]uint32, codec.Report, string, error) {
+	encoding := fromEncoding
+	if encoding == types.AUTO {
+		encoding = codec.Detect(data).Encoding
+		if len(encoding) == 0 {
+			encoding = types.UTF_8
+		}
+	}
+
+	if !isValidSourceEncoding(encoding) {
+		return nil, codec.Report{}, encoding, fmt.Errorf("invalid source encoding %v. use 'utfcoder list' to list the encodings", encoding)
+	}
+
+	codepoints, report, err := codec.Decode(data, encoding, options)
+	return codepoints, report, encoding, err
+}
+
+// registers -s and -from of the commands which only read a source
+func sourceFlags(fs *flag.FlagSet, sourceFile *string, fromEncoding *string) {
+	fs.StringVar(sourceFile, "s", "", "source `file` to read, - reads stdin")
+	lowerStringVar(fs, fromEncoding, "from", types.AUTO, "source file `encoding`, auto detects it")
+}
+
+func runHelp(c *cli, args []string) int {
+	if len(args) == 0 {
+		c.printUsage(c.stdout)
+		return exitOK
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok || cmd.name == "help" {
+		return c.fail(exitUsage, fmt.Sprintf("unknown command %q. use 'utfcoder help' to list the commands", args[0]))
+	}
+
+	// the usage of a command is its response to -h
+	helpCli := &cli{stdin: c.stdin, stdout: c.stdout, stderr: c.stdout}
+	cmd.run(helpCli, []string{"-h"})
+	return exitOK
+}
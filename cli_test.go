@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runs utfcoder with stdin and returns its exit code, stdout and stderr
+func runWith(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunExitCodes(t *testing.T) {
+	for _, test := range runTestInputs {
+		code, stdout, _ := runWith(test.args, test.stdin)
+
+		if code != test.code || !strings.Contains(stdout, test.stdout) {
+			t.Errorf(`run(%v) = code=%v, stdout=%q, Expected = code=%v, stdout containing %q`, test.args, code, stdout, test.code, test.stdout)
+		}
+	}
+}
+
+func TestRunConvertFile(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath, targetPath := filepath.Join(dir, "source.txt"), filepath.Join(dir, "target.txt")
+	os.WriteFile(sourcePath, []byte("hé"), 0600)
+
+	code, _, stderr := runWith([]string{"convert", "-s", sourcePath, "-t", targetPath, "-from", "UTF-8", "-to", "utf-16le"}, "")
+	output, _ := os.ReadFile(targetPath)
+	expected := []byte{'h', 0x00, 0xE9, 0x00}
+
+	if code != exitOK || !bytes.Equal(output, expected) {
+		t.Errorf(`run(convert %v) = code=%v, output=%v, stderr=%q, Expected = code=%v, output=%v`, sourcePath, code, output, stderr, exitOK, expected)
+	}
+}
+
+func TestRunConvertInPlace(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "source.txt")
+	os.WriteFile(sourcePath, []byte{'h', 0x00, 0xE9, 0x00}, 0640)
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(sourcePath, modTime, modTime)
+
+	// the source is only written over with -in-place
+	code, _, _ := runWith([]string{"convert", "-s", sourcePath, "-t", sourcePath, "-from", "utf-16le", "-to", "utf-8"}, "")
+	if code != exitUsage {
+		t.Errorf(`run(convert -t %v) = code=%v, Expected = code=%v`, sourcePath, code, exitUsage)
+	}
+
+	code, _, stderr := runWith([]string{"convert", "-s", sourcePath, "-in-place", "-backup", ".orig", "-from", "utf-16le", "-to", "utf-8"}, "")
+	output, _ := os.ReadFile(sourcePath)
+	backup, _ := os.ReadFile(sourcePath + ".orig")
+	info, _ := os.Stat(sourcePath)
+	expected := []byte("hé")
+
+	if code != exitOK || !bytes.Equal(output, expected) || len(backup) != 4 || info.Mode().Perm() != 0640 || !info.ModTime().Equal(modTime) {
+		t.Errorf(`run(convert -in-place %v) = code=%v, output=%v, backup=%v, mode=%v, modTime=%v, stderr=%q, Expected = code=%v, output=%v, backup of 4 bytes, mode=%v, modTime=%v`,
+			sourcePath, code, output, backup, info.Mode().Perm(), info.ModTime(), stderr, exitOK, expected, os.FileMode(0640), modTime)
+	}
+}
+
+func TestRunInPlaceSymlink(t *testing.T) {
+	dir := t.TempDir()
+	for _, command := range [][]string{{"convert", "-s"}, {"batch"}} {
+		sourcePath, linkPath := filepath.Join(dir, command[0]+".txt"), filepath.Join(dir, command[0]+"-link.txt")
+		os.WriteFile(sourcePath, []byte{'h', 0x00, 0xE9, 0x00}, 0644)
+		if err := os.Symlink(sourcePath, linkPath); err != nil {
+			t.Skip("no symbolic links:", err)
+		}
+
+		// the file the link points to is converted, the link stays a link
+		args := append([]string{command[0], "-in-place", "-from", "utf-16le", "-to", "utf-8"}, append(command[1:], linkPath)...)
+		code, _, stderr := runWith(args, "")
+		output, _ := os.ReadFile(sourcePath)
+		info, _ := os.Lstat(linkPath)
+		expected := []byte("hé")
+
+		if code != exitOK || !bytes.Equal(output, expected) || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf(`run(%v) = code=%v, output=%v, link mode=%v, stderr=%q, Expected = code=%v, output=%v, a symbolic link`, args, code, output, info.Mode(), stderr, exitOK, expected)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	sourceDir, outputDir := filepath.Join(dir, "source"), filepath.Join(dir, "output")
+	os.MkdirAll(filepath.Join(sourceDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(sourceDir, "sub", "report.txt"), []byte{'h', 0x00, 0xE9, 0x00}, 0644)
+	os.WriteFile(filepath.Join(sourceDir, "notes.txt"), []byte("plain"), 0644)
+	os.WriteFile(filepath.Join(sourceDir, "image.png"), []byte{0x89, 'P', 'N', 'G', 0x00, 0x00}, 0644)
+	os.WriteFile(filepath.Join(sourceDir, "skipped.log"), []byte{'l', 0x00, 'o', 0x00}, 0644)
+
+	code, stdout, stderr := runWith([]string{"batch", "-to", "utf-8", "-o", outputDir, "-exclude", "*.log", "-j", "2", sourceDir}, "")
+	output, _ := os.ReadFile(filepath.Join(outputDir, "sub", "report.txt"))
+	expected := []byte("hé")
+
+	if code != exitOK || !bytes.Equal(output, expected) || !strings.Contains(stdout, "3 files: 1 converted, 1 unchanged, 1 skipped") {
+		t.Errorf(`run(batch %v) = code=%v, output=%v, stdout=%q, stderr=%q, Expected = code=%v, output=%v`, sourceDir, code, output, stdout, stderr, exitOK, expected)
+	}
+
+	// a dry run only lists the files
+	code, stdout, _ = runWith([]string{"batch", "-to", "utf-8", "-dry-run", "-include", "sub/*", sourceDir}, "")
+	source, _ := os.ReadFile(filepath.Join(sourceDir, "sub", "report.txt"))
+
+	if code != exitOK || len(source) != 4 || !strings.Contains(stdout, "would convert (utf-16le -> utf-8)") {
+		t.Errorf(`run(batch -dry-run %v) = code=%v, source=%v, stdout=%q, Expected = code=%v, source unchanged`, sourceDir, code, source, stdout, exitOK)
+	}
+}
+
+func TestRunBatchJournal(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath, journalPath := filepath.Join(dir, "report.txt"), filepath.Join(dir, "journal.jsonl")
+	source := []byte{'h', 0x00, 0xE9, 0x00}
+	os.WriteFile(sourcePath, source, 0644)
+
+	args := []string{"batch", "-from", "utf-16le", "-to", "utf-8", "-in-place", "-backup", ".orig", "-journal", journalPath, dir}
+	runWith(args, "")
+
+	// without the journal the converted file would be decoded as utf-16le again
+	code, stdout, stderr := runWith(args, "")
+	output, _ := os.ReadFile(sourcePath)
+	expected := []byte("hé")
+
+	if code != exitOK || !bytes.Equal(output, expected) || !strings.Contains(stdout, "unchanged (converted by an earlier run)") {
+		t.Errorf(`run(batch -journal %v) = code=%v, output=%v, stdout=%q, stderr=%q, Expected = code=%v, output=%v`, journalPath, code, output, stdout, stderr, exitOK, expected)
+	}
+
+	code, stdout, stderr = runWith([]string{"revert", journalPath}, "")
+	output, _ = os.ReadFile(sourcePath)
+
+	if code != exitOK || !bytes.Equal(output, source) || !strings.Contains(stdout, "1 files: 1 restored") {
+		t.Errorf(`run(revert %v) = code=%v, output=%v, stdout=%q, stderr=%q, Expected = code=%v, output=%v`, journalPath, code, output, stdout, stderr, exitOK, source)
+	}
+}
+
+func TestRunWatchOnce(t *testing.T) {
+	dir := t.TempDir()
+	watchedDir, outputDir := filepath.Join(dir, "reports"), filepath.Join(dir, "output")
+	os.MkdirAll(watchedDir, 0755)
+	os.WriteFile(filepath.Join(watchedDir, "report.txt"), []byte{0xFF, 0xFE, 'h', 0x00, 0xE9, 0x00}, 0644)
+	os.WriteFile(filepath.Join(watchedDir, "report.tmp"), []byte("partial"), 0644)
+
+	args := []string{"watch", "-once", "-stable", "0", "-to", "utf-8", "-include", "*.txt", "-o", outputDir, watchedDir}
+	code, stdout, stderr := runWith(args, "")
+	output, _ := os.ReadFile(filepath.Join(outputDir, "report.txt"))
+	expected := []byte("hé")
+
+	if code != exitOK || !bytes.Equal(output, expected) || stdout != "report.txt: converted (utf-16le with bom -> utf-8)\n" {
+		t.Errorf(`run(watch -once %v) = code=%v, output=%v, stdout=%q, stderr=%q, Expected = code=%v, output=%v`, watchedDir, code, output, stdout, stderr, exitOK, expected)
+	}
+
+	// the state file keeps a restart from converting the file again
+	code, stdout, _ = runWith(args, "")
+	if code != exitOK || len(stdout) != 0 {
+		t.Errorf(`run(watch -once %v) again = code=%v, stdout=%q, Expected = code=%v, stdout=""`, watchedDir, code, stdout, exitOK)
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	dir := t.TempDir()
+	utf16Path, utf8Path := filepath.Join(dir, "report_utf16.txt"), filepath.Join(dir, "report_utf8.txt")
+	os.WriteFile(utf16Path, []byte{0xFF, 0xFE, 'a', 0x00, '\r', 0x00, '\n', 0x00, 0xE9, 0x00}, 0644)
+	os.WriteFile(utf8Path, []byte("a\ne\u0301"), 0644)
+
+	for _, test := range compareTestInputs {
+		code, stdout, _ := runWith(append(append([]string{"compare"}, test.flags...), utf16Path, utf8Path), "")
+
+		if code != test.code || !strings.Contains(stdout, test.stdout) {
+			t.Errorf(`run(compare %v) = code=%v, stdout=%q, Expected = code=%v, stdout containing %q`, test.flags, code, stdout, test.code, test.stdout)
+		}
+	}
+}
+
+func TestRunCat(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "export_utf8.csv"), filepath.Join(dir, "export_utf16be.csv"), filepath.Join(dir, "export_utf32le.csv")}
+	os.WriteFile(paths[0], []byte("\xef\xbb\xbfid;name"), 0644)
+	os.WriteFile(paths[1], []byte{0xFE, 0xFF, 0x00, '1', 0x00, ';', 0x00, 0xE9, 0x00, '\r', 0x00, '\n'}, 0644)
+	os.WriteFile(paths[2], []byte{'2', 0x00, 0x00, 0x00}, 0644)
+	targetPath := filepath.Join(dir, "export.csv")
+
+	code, _, stderr := runWith([]string{"cat", "-to", "utf-8", "-bom", "-eol", "lf", "-t", targetPath, paths[0], paths[1], paths[2] + ":utf-32le"}, "")
+	output, _ := os.ReadFile(targetPath)
+	expected := "\xef\xbb\xbfid;name\n1;é\n2"
+
+	if code != exitOK || string(output) != expected {
+		t.Errorf(`run(cat %v) = code=%v, output=%q, stderr=%q, Expected = code=%v, output=%q`, paths, code, output, stderr, exitOK, expected)
+	}
+}
+
+func TestRunRename(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "Pr\xe9sentations"), 0755)
+	os.WriteFile(filepath.Join(dir, "Pr\xe9sentations", "R\xe9sum\xe9.doc"), []byte("cv"), 0644)
+	os.WriteFile(filepath.Join(dir, "\x93\xfa\x96\x7b.txt"), []byte("jp"), 0644)
+	os.WriteFile(filepath.Join(dir, "Cafe\u0301 menu.txt"), []byte("nfd"), 0644)
+	// the utf-8 name is taken, the windows-1252 one must not replace it
+	os.WriteFile(filepath.Join(dir, "caf\xe9.txt"), []byte("old"), 0644)
+	os.WriteFile(filepath.Join(dir, "café.txt"), []byte("new"), 0644)
+
+	code, stdout, _ := runWith([]string{"rename", "-nfc", "-dry-run", dir}, "")
+	if _, err := os.Stat(filepath.Join(dir, "\x93\xfa\x96\x7b.txt")); code != exitFailure || err != nil || strings.Count(stdout, "would rename to") != 4 {
+		t.Errorf(`run(rename -dry-run) = code=%v, stdout=%q, Expected = code=%v, 4 names which would be renamed and nothing renamed`, code, stdout, exitFailure)
+	}
+
+	code, stdout, _ = runWith([]string{"rename", "-nfc", dir}, "")
+	for _, name := range []string{"Présentations/Résumé.doc", "日本.txt", "Café menu.txt", "caf\xe9.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf(`run(rename) = code=%v, stdout=%q, Expected = %q`, code, stdout, name)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "café.txt")); code != exitFailure || string(data) != "new" {
+		t.Errorf(`run(rename) = code=%v, café.txt=%q, Expected = code=%v, café.txt="new"`, code, data, exitFailure)
+	}
+}
+
+func TestRunGen(t *testing.T) {
+	dir := t.TempDir()
+	targetPath := filepath.Join(dir, "corpus.txt")
+
+	code, _, stderr := runWith([]string{"gen", "-to", "utf-16le", "-n", "100", "-seed", "5", "-lone-surrogates", "0.1", "-truncated-tail", "-t", targetPath}, "")
+	output, _ := os.ReadFile(targetPath)
+	manifest, _ := os.ReadFile(targetPath + ".manifest.json")
+
+	if code != exitOK || len(output) == 0 || !strings.Contains(string(manifest), `"seed": 5`) || !strings.Contains(string(manifest), `"kind": "truncated-tail"`) {
+		t.Errorf(`run(gen) = code=%v, output=%v bytes, manifest=%q, stderr=%q, Expected = code=%v, a manifest of seed 5 with a truncated tail`, code, len(output), manifest, stderr, exitOK)
+	}
+
+	// the same seed generates the same file
+	_, again, _ := runWith([]string{"gen", "-to", "utf-16le", "-n", "100", "-seed", "5", "-lone-surrogates", "0.1", "-truncated-tail"}, "")
+	if again != string(output) {
+		t.Errorf(`run(gen -seed 5) = %x, Expected = %x`, again, output)
+	}
+}
+
+func TestRunGrep(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "de"), 0755)
+	os.WriteFile(filepath.Join(dir, "customers_utf16.txt"), []byte{0xFF, 0xFE, 'M', 0x00, 0xFC, 0x00, 'l', 0x00, 'l', 0x00, 'e', 0x00, 'r', 0x00}, 0644)
+	os.WriteFile(filepath.Join(dir, "de", "customers.txt"), []byte("Anna\r\nHans Mu\u0308ller\n"), 0644)
+
+	for _, test := range grepTestInputs {
+		code, stdout, _ := runWith(append(append([]string{"grep"}, test.flags...), "Müller", dir), "")
+
+		if code != test.code || strings.Count(stdout, "\n") != len(test.matches) {
+			t.Errorf(`run(grep %v) = code=%v, stdout=%q, Expected = code=%v, matches=%q`, test.flags, code, stdout, test.code, test.matches)
+			continue
+		}
+		for _, match := range test.matches {
+			if !strings.Contains(stdout, match) {
+				t.Errorf(`run(grep %v) = stdout=%q, Expected = stdout containing %q`, test.flags, stdout, match)
+			}
+		}
+	}
+}
+
+var grepTestInputs = []struct {
+	flags   []string
+	code    int
+	matches []string
+}{
+	{[]string{}, exitOK, []string{"customers_utf16.txt:1:1:2:Müller"}},
+	// the offset is the byte offset in the file, the column counts the code points of the line
+	{[]string{"-ignore-normalization"}, exitOK, []string{"customers_utf16.txt:1:1:2:Müller", "customers.txt:2:6:11:Hans Mu\u0308ller"}},
+	{[]string{"-include", "*.csv"}, exitInvalid, nil},
+}
+
+var compareTestInputs = []struct {
+	flags  []string
+	code   int
+	stdout string
+}{
+	{[]string{}, exitInvalid, "report_utf16.txt: code point 0 (line 1, column 1, offset 0): U+FEFF ZERO WIDTH NO-BREAK SPACE"},
+	{[]string{"-ignore-bom"}, exitInvalid, "report_utf8.txt: code point 1 (line 1, column 2, offset 1): U+000A <control>"},
+	{[]string{"-ignore-bom", "-ignore-eol"}, exitInvalid, "report_utf8.txt: code point 2 (line 2, column 1): U+0065 LATIN SMALL LETTER E"},
+	{[]string{"-ignore-bom", "-ignore-eol", "-ignore-normalization"}, exitOK, "equal"},
+	// the hash of the code points is the same in every encoding
+	{[]string{"-ignore-bom", "-ignore-eol", "-ignore-normalization"}, exitOK, "report_utf8.txt: utf-8, 3 code points, sha256 "},
+}
+
+var runTestInputs = []struct {
+	args   []string
+	stdin  string
+	code   int
+	stdout string
+}{
+	{[]string{}, "", exitUsage, ""},
+	{[]string{"-h"}, "", exitOK, "exit codes:"},
+	{[]string{"help", "validate"}, "", exitOK, "usage: utfcoder validate"},
+	{[]string{"unknown"}, "", exitUsage, ""},
+	{[]string{"convert", "-unknown"}, "", exitUsage, ""},
+	{[]string{"convert", "-s", "-", "-from", "utf-8"}, "", exitUsage, ""},
+	{[]string{"convert", "-s", "missing.txt", "-from", "utf-8", "-to", "utf-16"}, "", exitFailure, ""},
+	// without a command the flags are those of convert
+	{[]string{"-s", "-", "-from", "utf-8", "-to", "escaped-json"}, "hé", exitOK, `h\u00e9`},
+	// a grapheme cluster larger than the limit is cut off with the rest
+	{[]string{"-s", "-", "-from", "utf-16le", "-to", "utf-8", "-max-bytes", "3"}, "a\x00b\x00\x3D\xD8\x68\xDC\x0D\x20\x3D\xD8\x69\xDC", exitOK, "ab"},
+	{[]string{"-s", "-", "-from", "utf-8", "-to", "utf-16le", "-max-units", "4"}, "a\U0001F468\u200D\U0001F469", exitOK, "a\x00"},
+	{[]string{"detect", "-"}, "h\x00i\x00", exitOK, "-: utf-16le"},
+	{[]string{"detect", "-"}, "h\xe9", exitInvalid, "-: unknown"},
+	{[]string{"validate", "-s", "-", "-from", "utf-8"}, "ok", exitOK, "valid utf-8"},
+	{[]string{"validate", "-s", "-", "-from", "utf-8"}, "o\xffk", exitInvalid, "offset 1 (line 1, column 2): invalid byte [FF]"},
+	{[]string{"validate", "-"}, "a\n\xe2\x82", exitInvalid, "offset 2 (line 2, column 1): truncated sequence [E2 82]"},
+	// escaped sources have no byte offsets to print
+	{[]string{"inspect", "-from", "escaped-json", "-"}, `\u00e9`, exitUsage, ""},
+	{[]string{"grep", "-from", "percent", "e", "-"}, "%C3%A9", exitUsage, ""},
+	{[]string{"grep", "e", "-:html-entities"}, "&eacute;", exitUsage, ""},
+	{[]string{"inspect", "-"}, "é", exitOK, "00000000  C3 A9        U+00E9    Ll  C3 A9        00 E9        00 00 00 E9  LATIN SMALL LETTER E WITH ACUTE"},
+	{[]string{"inspect", "-at", "2", "-context", "0", "-"}, "ab\xffc", exitOK, "00000002  FF           -         -   -            -            -            <undecodable byte>  [invalid: invalid byte]"},
+	{[]string{"stats", "-"}, "a\r\nb\n", exitOK, "line endings: LF 1, CRLF 1"},
+	{[]string{"char", "U+2019"}, "", exitOK, "utf-16le        19 20        with bom FF FE 19 20"},
+	{[]string{"char", "right single quotation mark"}, "", exitOK, "U+2019  RIGHT SINGLE QUOTATION MARK (Pf)"},
+	{[]string{"char", "E2 80 99"}, "", exitOK, "utf-8           valid    U+2019"},
+	{[]string{"char", "😀"}, "", exitOK, "high 0xD800 + 0000111101 = D83D, low 0xDC00 + 1000000000 = DE00"},
+	{[]string{"char", "é"}, "", exitOK, "payload bits    00011 101001 = 0xE9"},
+	{[]string{"char", "0xZZ"}, "", exitUsage, ""},
+	{[]string{"batch", "-to", "utf-8"}, "", exitUsage, ""},
+	{[]string{"batch", "-to", "utf-8", "."}, "", exitUsage, ""},
+	{[]string{"revert"}, "", exitUsage, ""},
+	{[]string{"revert", "missing.jsonl"}, "", exitFailure, ""},
+	{[]string{"watch", "-to", "utf-8", "."}, "", exitUsage, ""},
+	{[]string{"convert", "-follow", "-s", "-", "-from", "utf-16le", "-to", "utf-8"}, "h\x00\n\x00i\x00", exitOK, "h\ni"},
+	{[]string{"convert", "-follow", "-s", "-", "-from", "escaped-json", "-to", "utf-8"}, "", exitUsage, ""},
+	{[]string{"compare", "-"}, "", exitUsage, ""},
+	{[]string{"cat", "-to", "utf-8", "--", "-:utf-16le"}, "\xff\xfeh\x00i\x00", exitOK, "hi"},
+	{[]string{"cat", "-to", "utf-8"}, "", exitUsage, ""},
+	{[]string{"grep", "-i", "(?:HI)", "-"}, "h\x00i\x00", exitOK, "-:1:1:0:hi"},
+	{[]string{"grep", "bye", "-"}, "hi", exitInvalid, ""},
+	{[]string{"grep", "(", "-"}, "", exitUsage, ""},
+	{[]string{"strings", "-"}, "\x00\x00M\x00\xfc\x00l\x00l\x00e\x00r\x00\x00\x00", exitOK, "-:2:utf-16le:Müller"},
+	{[]string{"strings", "-class", "ascii", "-"}, "\x00\x00M\x00\xfc\x00l\x00l\x00e\x00r\x00\x00\x00", exitOK, "-:6:utf-16le:ller"},
+	{[]string{"strings", "-script", "klingon", "-"}, "", exitUsage, ""},
+	{[]string{"strings", "-e", "utf-7", "-"}, "", exitUsage, ""},
+	{[]string{"gen", "-to", "utf-8", "-n", "3", "-ascii", "1", "-bmp", "0", "-astral", "0", "-combining", "0"}, "", exitOK, ""},
+	{[]string{"gen", "-to", "utf-16be", "-beyond-max", "0.1"}, "", exitUsage, ""},
+	{[]string{"gen", "-to", "utf-8", "-wrong-endian-bom"}, "", exitUsage, ""},
+	{[]string{"gen", "-to", "utf-7"}, "", exitUsage, ""},
+	{[]string{"rename"}, "", exitUsage, ""},
+	{[]string{"rename", "-from", "utf-16le", "."}, "", exitUsage, ""},
+	{[]string{"convert", "-verify", "-s", "-", "-from", "utf-8", "-to", "utf-16le"}, "h\xf0\x9f\x98\x80", exitOK, "h\x00=\xd8\x00\xde"},
+	{[]string{"convert", "-verify", "-s", "-", "-from", "utf-8", "-to", "utf-16le"}, "a\xe9b", exitFailure, ""},
+	{[]string{"convert", "-verify", "-errors", "surrogateescape", "-s", "-", "-from", "utf-8", "-to", "utf-16be"}, "a\xe9b", exitOK, "\x00a\xdc\xe9\x00b"},
+	{[]string{"convert", "-verify", "-follow", "-s", "-", "-from", "utf-8", "-to", "utf-32le"}, "h\n\xe9\n", exitFailure, ""},
+	{[]string{"list"}, "", exitOK, "escaped-json"},
+}
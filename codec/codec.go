@@ -0,0 +1,176 @@
+package codec
+
+import (
+	"errors"
+	"strings"
+	"utfcoder/charset"
+	"utfcoder/escape"
+	"utfcoder/logger"
+	"utfcoder/mojibake"
+	"utfcoder/transform"
+	"utfcoder/types"
+	UTF16 "utfcoder/utf16"
+	UTF32 "utfcoder/utf32"
+	UTF8 "utfcoder/utf8"
+)
+
+type Options struct {
+	// prefix the output with the byte order mark of the target encoding
+	AddBOM bool
+	// what to do with undecodable bytes and unencodable code points
+	ErrorMode types.ErrorMode
+	// which code points the escaped flavors write as escape sequences
+	EscapeScope types.EscapeScope
+	// charset the character reference encodings write unescaped, ascii or latin-1
+	Charset string
+	// decode utf-8 sources line by line, lines which aren't utf-8 with this charset instead, unless empty
+	Fallback string
+	// check the decoded text for code points XML 1.0 forbids, unless empty
+	XMLCheck types.XMLCheck
+	// undo utf-8 misread as windows-1252 or latin-1, line by line
+	FixMojibake bool
+	// normalize the decoded text to this form, unless empty
+	Normalize types.NormalizationForm
+	// rewrite every line end to this style, unless empty. keep only counts them
+	EOL types.LineEnding
+	// truncate the output at a grapheme cluster boundary to at most this many bytes, unless 0
+	MaxBytes int
+	// decode the output again and check it against the decoded text
+	Verify bool
+}
+
+// Report collects what decoding found in the input besides the code points
+type Report struct {
+	// number of bytes carried as surrogate escapes
+	Escaped int
+	// malformed sequences of the input, or malformed escape sequences and character references of escaped text sources
+	Malformed []types.Malformed
+	// 1-based numbers of the lines decoded with Fallback
+	FallbackLines []int
+	// code points rejected by the checks on the decoded text, their offset is the code point index
+	Rejected []types.Malformed
+	// number of line ends of every style in the input, when EOL is set
+	LineEndings map[types.LineEnding]int
+	// lines whose mojibake was undone, when FixMojibake is set
+	Repairs []mojibake.Repair
+	// the output was cut short to fit MaxBytes
+	Truncated bool
+}
+
+func Decode(input []byte, sourceEncoding string, options Options) ([]uint32, Report, error) {
+	var codepoints []uint32
+	var report Report
+
+	switch sourceEncoding {
+	case types.UTF_8:
+		if len(options.Fallback) != 0 {
+			codepoints, report.Escaped, report.FallbackLines = charset.DecodeMixed(input, options.Fallback, options.ErrorMode)
+			break
+		}
+		codepoints, report.Escaped, report.Malformed = UTF8.Decode(input, options.ErrorMode)
+	case types.UTF_16, types.UTF_16LE, types.UTF_16BE:
+		codepoints, report.Escaped, report.Malformed = UTF16.Decode(input, sourceEncoding, options.ErrorMode)
+	case types.UTF_32, types.UTF_32LE, types.UTF_32BE:
+		// without escaping, a length which isn't a multiple of 4 means the input is not utf-32 at all
+		if len(input)%4 != 0 && options.ErrorMode != types.SURROGATE_ESCAPE {
+			return nil, report, errors.New("invalid input")
+		}
+		codepoints, report.Escaped, report.Malformed = UTF32.Decode(input, sourceEncoding, options.ErrorMode)
+	default:
+		if !escape.IsSourceEncoding(sourceEncoding) {
+			return nil, report, errors.New(strings.ToUpper(sourceEncoding) + " decoding not implemented")
+		}
+		codepoints, report.Escaped, report.Malformed = escape.Decode(input, sourceEncoding, options.Charset, options.ErrorMode)
+	}
+
+	return codepoints, report, nil
+}
+
+func Encode(codepoints []uint32, targetEncoding string, options Options) ([]byte, error) {
+	switch targetEncoding {
+	case types.UTF_8:
+		return UTF8.Encode(codepoints, options.AddBOM, options.ErrorMode), nil
+	case types.UTF_16, types.UTF_16LE, types.UTF_16BE:
+		return UTF16.Encode(codepoints, targetEncoding, options.AddBOM, options.ErrorMode), nil
+	case types.UTF_32, types.UTF_32LE, types.UTF_32BE:
+		return UTF32.Encode(codepoints, targetEncoding, options.AddBOM, options.ErrorMode), nil
+	}
+
+	if escape.IsFlavor(targetEncoding) {
+		// escaped text is plain ascii, so there is no byte order mark to add
+		return escape.Encode(codepoints, targetEncoding, options.EscapeScope, options.ErrorMode), nil
+	}
+
+	if escape.IsReferenceEncoding(targetEncoding) {
+		return escape.EncodeReferences(codepoints, targetEncoding, options.Charset, options.ErrorMode), nil
+	}
+
+	return nil, errors.New(strings.ToUpper(targetEncoding) + " encoding not implemented")
+}
+
+// runs the stages selected in options on the decoded code points
+func Apply(codepoints []uint32, options Options, report *Report) []uint32 {
+	// mojibake is undone first, the other stages expect the text as it was meant to be
+	if options.FixMojibake {
+		codepoints, report.Repairs = mojibake.Fix(codepoints)
+	}
+
+	if len(options.Normalize) != 0 {
+		codepoints = transform.Normalize(codepoints, options.Normalize)
+	}
+
+	if len(options.EOL) != 0 {
+		codepoints, report.LineEndings = transform.ConvertLineEndings(codepoints, options.EOL)
+	}
+
+	if len(options.XMLCheck) != 0 {
+		codepoints, report.Rejected = transform.CheckXML(codepoints, options.XMLCheck)
+	}
+
+	return codepoints
+}
+
+func Convert(input []byte, sourceEncoding string, targetEncoding string, options Options) ([]byte, Report, error) {
+	logger.Log("\nConvert", strings.ToUpper(sourceEncoding), input, "To", strings.ToUpper(targetEncoding))
+
+	codepoints, report, err := Decode(input, sourceEncoding, options)
+	if err != nil {
+		return nil, report, err
+	}
+
+	codepoints = Apply(codepoints, options, &report)
+
+	var output []byte
+	if options.MaxBytes > 0 {
+		output, report.Truncated, err = Truncate(codepoints, targetEncoding, options, options.MaxBytes)
+	} else {
+		output, err = Encode(codepoints, targetEncoding, options)
+	}
+	if err != nil {
+		return nil, report, err
+	}
+
+	if options.Verify {
+		expected, err := Expected(input, sourceEncoding, codepoints, options)
+		if err != nil {
+			return nil, report, err
+		}
+
+		v := NewVerifier(targetEncoding, options)
+		v.Expect(expected)
+		if err := v.Write(output); err != nil {
+			return nil, report, err
+		}
+		// truncated output only has to decode back to the start of the text
+		if err = v.Err(); !report.Truncated {
+			err = v.Close()
+		}
+		if err != nil {
+			return nil, report, err
+		}
+	}
+
+	logger.Log("\nConverted to", targetEncoding, output)
+
+	return output, report, nil
+}
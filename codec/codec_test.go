@@ -0,0 +1,47 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+	"utfcoder/types"
+)
+
+func TestSurrogateEscapeRoundTrip(t *testing.T) {
+	for _, target := range []string{types.UTF_16, types.UTF_16LE, types.UTF_32, types.UTF_32LE} {
+		for _, input := range surrogateEscapeTestInputs {
+			encoded, escaped, err := Convert(input, types.UTF_8, target, escapeOptions)
+			if err != nil {
+				t.Fatalf(`Convert(%v, %v) = error=%v, Expected = error=%v`, input, target, err, nil)
+			}
+
+			output, restored, err := Convert(encoded, target, types.UTF_8, escapeOptions)
+			if !bytes.Equal(input, output) || restored.Escaped != escaped.Escaped || err != nil {
+				t.Errorf(`Convert(Convert(%v, %v)) = output=%v, escaped=%v, error=%v, Expected = output=%v, escaped=%v, error=%v`, input, target, output, restored, err, input, escaped, nil)
+			}
+		}
+	}
+}
+
+func TestReplaceDropsEscapes(t *testing.T) {
+	input := []byte{0x43, 0x61, 0x66, 0xE9}
+	expected := []byte{0x43, 0x61, 0x66, 0xEF, 0xBF, 0xBD}
+
+	encoded, _, _ := Convert(input, types.UTF_8, types.UTF_16LE, escapeOptions)
+	output, _, err := Convert(encoded, types.UTF_16LE, types.UTF_8, Options{ErrorMode: types.REPLACE})
+
+	if !bytes.Equal(expected, output) || err != nil {
+		t.Errorf(`Convert(%v) = output=%v, error=%v, Expected = output=%v, error=%v`, encoded, output, err, expected, nil)
+	}
+}
+
+// utf-8 inputs with stray bytes which must survive a trip through utf-16/utf-32
+var surrogateEscapeTestInputs = [][]byte{
+	{},
+	{0x43, 0x61, 0x66, 0xE9},       // "Caf" + latin-1 é
+	{0xC3, 0xA9, 0x3D, 0xE9, 0x0A}, // "é=" + latin-1 é + LF
+	{0x80, 0x81, 0xFE, 0xFF},       // stray continuation and invalid lead bytes
+	{0xF0, 0x9F, 0x98, 0x80, 0xF0, 0x9F, 0x98}, // 😀 followed by a truncated 😀
+	{0xED, 0xB2, 0x80},                         // utf-8 encoded escape U+DC80 itself
+}
+
+var escapeOptions = Options{ErrorMode: types.SURROGATE_ESCAPE}
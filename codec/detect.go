@@ -0,0 +1,93 @@
+package codec
+
+import (
+	"bytes"
+	"unicode/utf8"
+	"utfcoder/types"
+)
+
+// Detection is the encoding input most likely has and why
+type Detection struct {
+	// empty when the encoding couldn't be told
+	Encoding string
+	HasBOM   bool
+	Reason   string
+}
+
+var byteOrderMarks = []struct {
+	bom      []byte
+	encoding string
+}{
+	// the utf-32le mark starts with the utf-16le one, so it is checked first
+	{[]byte{0xFF, 0xFE, 0x00, 0x00}, types.UTF_32LE},
+	{[]byte{0x00, 0x00, 0xFE, 0xFF}, types.UTF_32BE},
+	{[]byte{0xEF, 0xBB, 0xBF}, types.UTF_8},
+	{[]byte{0xFF, 0xFE}, types.UTF_16LE},
+	{[]byte{0xFE, 0xFF}, types.UTF_16BE},
+}
+
+// returns the number of NUL bytes at every position modulo 4
+func countNULs(input []byte) [4]int {
+	var counts [4]int
+	for idx, b := range input {
+		if b == 0 {
+			counts[idx%4] += 1
+		}
+	}
+	return counts
+}
+
+// guesses the encoding of input from its byte order mark, else from where its NUL bytes are. text is mostly
+// ascii, which is NUL padded in utf-16 and utf-32
+func Detect(input []byte) Detection {
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(input, mark.bom) {
+			return Detection{Encoding: mark.encoding, HasBOM: true, Reason: "byte order mark"}
+		}
+	}
+
+	if len(input) == 0 {
+		return Detection{Encoding: types.UTF_8, Reason: "empty input"}
+	}
+
+	nuls := countNULs(input)
+	units := (len(input) + 3) / 4
+
+	// the highest byte of a utf-32 code unit is always NUL, the one below it is for the whole BMP
+	if len(input)%4 == 0 && nuls[3] == units && nuls[2]*4 >= units*3 {
+		return Detection{Encoding: types.UTF_32LE, Reason: "NUL padding of utf-32le"}
+	}
+	if len(input)%4 == 0 && nuls[0] == units && nuls[1]*4 >= units*3 {
+		return Detection{Encoding: types.UTF_32BE, Reason: "NUL padding of utf-32be"}
+	}
+
+	// half of the bytes, the even or the odd ones, hold the high byte of every utf-16 code unit
+	even, odd := nuls[0]+nuls[2], nuls[1]+nuls[3]
+	if len(input)%2 == 0 && odd*4 > len(input) && even*8 < odd {
+		return Detection{Encoding: types.UTF_16LE, Reason: "NUL padding of utf-16le"}
+	}
+	if len(input)%2 == 0 && even*4 > len(input) && odd*8 < even {
+		return Detection{Encoding: types.UTF_16BE, Reason: "NUL padding of utf-16be"}
+	}
+
+	if utf8.Valid(input) {
+		return Detection{Encoding: types.UTF_8, Reason: "valid utf-8"}
+	}
+
+	return Detection{Reason: "not utf-8 and no NUL padding of utf-16 or utf-32"}
+}
+
+// number of leading bytes IsBinary looks at
+const binarySniffLength = 8000
+
+// returns whether input looks like binary data rather than text: a NUL byte in its first bytes which isn't the
+// padding of utf-16 or utf-32 text
+func IsBinary(input []byte) bool {
+	sample := input[:min(len(input), binarySniffLength)]
+
+	switch Detect(sample).Encoding {
+	case types.UTF_16LE, types.UTF_16BE, types.UTF_32LE, types.UTF_32BE:
+		return false
+	}
+	return bytes.IndexByte(sample, 0) >= 0
+}
@@ -0,0 +1,58 @@
+package codec
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+func TestDetect(t *testing.T) {
+	for _, test := range detectTestInputs {
+		detection := Detect(test.input)
+
+		if detection.Encoding != test.encoding || detection.HasBOM != test.hasBOM {
+			t.Errorf(`Detect(%v) = encoding=%v, hasBOM=%v, Expected = encoding=%v, hasBOM=%v`, test.input, detection.Encoding, detection.HasBOM, test.encoding, test.hasBOM)
+		}
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	for _, test := range isBinaryTestInputs {
+		isBinary := IsBinary(test.input)
+
+		if isBinary != test.isBinary {
+			t.Errorf(`IsBinary(%v) = isBinary=%v, Expected = isBinary=%v`, test.input, isBinary, test.isBinary)
+		}
+	}
+}
+
+var detectTestInputs = []struct {
+	input    []byte
+	encoding string
+	hasBOM   bool
+}{
+	{[]byte{0xEF, 0xBB, 0xBF, 'a'}, types.UTF_8, true},
+	{[]byte{0xFF, 0xFE, 'a', 0x00}, types.UTF_16LE, true},
+	{[]byte{0xFE, 0xFF, 0x00, 'a'}, types.UTF_16BE, true},
+	{[]byte{0xFF, 0xFE, 0x00, 0x00, 'a', 0x00, 0x00, 0x00}, types.UTF_32LE, true},
+	{[]byte{0x00, 0x00, 0xFE, 0xFF}, types.UTF_32BE, true},
+	{[]byte{'h', 0x00, 'i', 0x00}, types.UTF_16LE, false},
+	{[]byte{0x00, 'h', 0x00, 'i'}, types.UTF_16BE, false},
+	{[]byte{'h', 0x00, 0x00, 0x00, 'i', 0x00, 0x00, 0x00}, types.UTF_32LE, false},
+	{[]byte{0x00, 0x00, 0x00, 'h', 0x00, 0x00, 0x00, 'i'}, types.UTF_32BE, false},
+	{[]byte{'c', 'a', 'f', 0xC3, 0xA9}, types.UTF_8, false},
+	{[]byte{'c', 'a', 'f', 0xE9}, "", false},
+	{[]byte{}, types.UTF_8, false},
+}
+
+var isBinaryTestInputs = []struct {
+	input    []byte
+	isBinary bool
+}{
+	{[]byte("plain text\n"), false},
+	{[]byte{'c', 'a', 'f', 0xE9}, false},
+	{[]byte{'h', 0x00, 'i', 0x00}, false},
+	{[]byte{0xFF, 0xFE, 0x00, 0x00, 'a', 0x00, 0x00, 0x00}, false},
+	{[]byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A, 0x00, 0x00, 0x00, 0x0D}, true},
+	{[]byte{'a', 'b', 'c', 0x00, 'd'}, true},
+	{[]byte{}, false},
+}
@@ -0,0 +1,78 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unicode/utf8"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+// Span is where a decoded code point came from in the input
+type Span struct {
+	Offset, Size int
+}
+
+// returns whether Decode of sourceEncoding skips mark
+func skipsBOM(sourceEncoding string, markEncoding string) bool {
+	switch sourceEncoding {
+	case types.UTF_16:
+		return markEncoding == types.UTF_16LE || markEncoding == types.UTF_16BE
+	case types.UTF_32:
+		return markEncoding == types.UTF_32LE || markEncoding == types.UTF_32BE
+	}
+	return sourceEncoding == markEncoding
+}
+
+// returns the length of the byte order mark Decode skips at the start of input
+func BOMLength(input []byte, sourceEncoding string) int {
+	for _, mark := range byteOrderMarks {
+		if skipsBOM(sourceEncoding, mark.encoding) && bytes.HasPrefix(input, mark.bom) {
+			return len(mark.bom)
+		}
+	}
+	return 0
+}
+
+// returns the bytes of input every code point came from. codepoints must be what Decode returned for input in
+// surrogateescape mode, where every undecodable byte is a code point of its own
+func Spans(input []byte, sourceEncoding string, codepoints []uint32) ([]Span, error) {
+	var spans = make([]Span, 0, len(codepoints))
+
+	offset := BOMLength(input, sourceEncoding)
+	for _, bits := range codepoints {
+		size := 1
+
+		switch sourceEncoding {
+		case types.UTF_8:
+			if !utils.IsEscapedByte(bits) {
+				size = utf8.RuneLen(rune(bits))
+			}
+		case types.UTF_16, types.UTF_16LE, types.UTF_16BE:
+			if bits >= 0x10000 {
+				size = 4
+			} else if offset+1 < len(input) {
+				// only a trailing odd byte is a code point of a single byte
+				size = 2
+			}
+		case types.UTF_32, types.UTF_32LE, types.UTF_32BE:
+			if offset+3 < len(input) {
+				size = 4
+			} else if !utils.IsEscapedByte(bits) {
+				// the first trailing byte which can't be escaped is replaced together with the rest
+				size = len(input) - offset
+			}
+		default:
+			return nil, errors.New("cannot locate the code points of " + strings.ToUpper(sourceEncoding) + " sources")
+		}
+
+		if offset+size > len(input) {
+			return nil, errors.New("code points don't match the input")
+		}
+		spans = append(spans, Span{Offset: offset, Size: size})
+		offset += size
+	}
+
+	return spans, nil
+}
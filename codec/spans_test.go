@@ -0,0 +1,30 @@
+package codec
+
+import (
+	"slices"
+	"testing"
+	"utfcoder/types"
+)
+
+func TestSpans(t *testing.T) {
+	for _, test := range spansTestInputs {
+		codepoints, _, _ := Decode(test.input, test.encoding, Options{ErrorMode: types.SURROGATE_ESCAPE})
+		spans, err := Spans(test.input, test.encoding, codepoints)
+
+		if err != nil || !slices.Equal(spans, test.expected) {
+			t.Errorf(`Spans(%v, %v) = spans=%v, error=%v, Expected = spans=%v`, test.input, test.encoding, spans, err, test.expected)
+		}
+	}
+}
+
+var spansTestInputs = []struct {
+	input    []byte
+	encoding string
+	expected []Span
+}{
+	// BOM, "a", "é", a truncated sequence
+	{[]byte{0xEF, 0xBB, 0xBF, 'a', 0xC3, 0xA9, 0xE2, 0x82}, types.UTF_8, []Span{{3, 1}, {4, 2}, {6, 1}, {7, 1}}},
+	// BOM, "a", a surrogate pair, a lone surrogate, an odd byte
+	{[]byte{0xFF, 0xFE, 'a', 0x00, 0x3D, 0xD8, 0x00, 0xDE, 0x00, 0xD8, 'x'}, types.UTF_16, []Span{{2, 2}, {4, 4}, {8, 2}, {10, 1}}},
+	{[]byte{'a', 0x00, 0x00, 0x00, 0xFF, 'b'}, types.UTF_32LE, []Span{{0, 4}, {4, 1}, {5, 1}}},
+}
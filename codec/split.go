@@ -0,0 +1,90 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"utfcoder/transform"
+	"utfcoder/types"
+)
+
+// returns the number of bytes per code unit of targetEncoding, used to turn a limit in code units into bytes
+func UnitSize(targetEncoding string) int {
+	switch targetEncoding {
+	case types.UTF_16, types.UTF_16LE, types.UTF_16BE:
+		return 2
+	case types.UTF_32, types.UTF_32LE, types.UTF_32BE:
+		return 4
+	}
+	return 1
+}
+
+// returns codepoints encoded in targetEncoding, cut at grapheme cluster boundaries into at most maxParts parts
+// of at most maxBytes bytes each (byte order mark included), and whether every code point made it into a part.
+// maxParts 0 means no limit. a byte order mark larger than maxBytes is an error
+func split(codepoints []uint32, targetEncoding string, options Options, maxBytes int, maxParts int) ([][]byte, bool, error) {
+	bom, err := Encode(nil, targetEncoding, options)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(bom) > maxBytes {
+		return nil, false, errors.New(fmt.Sprint("the byte order mark needs ", len(bom), " bytes, more than the limit of ", maxBytes))
+	}
+
+	// clusters are encoded one by one, only the part gets the byte order mark
+	clusterOptions := options
+	clusterOptions.AddBOM = false
+
+	var parts [][]byte
+	part := append([]byte{}, bom...)
+	offset := 0
+
+	for _, cluster := range transform.GraphemeClusters(codepoints) {
+		encoded, err := Encode(cluster, targetEncoding, clusterOptions)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(bom)+len(encoded) > maxBytes {
+			// a cluster no part can hold ends a truncation, only split can't go on without it
+			if maxParts == 1 {
+				return [][]byte{part}, false, nil
+			}
+			return nil, false, errors.New(fmt.Sprint("grapheme cluster at code point ", offset, " needs ", len(bom)+len(encoded), " bytes, more than the limit of ", maxBytes))
+		}
+
+		if len(part)+len(encoded) > maxBytes {
+			parts = append(parts, part)
+			if maxParts != 0 && len(parts) == maxParts {
+				return parts, false, nil
+			}
+			part = append([]byte{}, bom...)
+		}
+
+		part = append(part, encoded...)
+		offset += len(cluster)
+	}
+
+	// empty text is no part at all, a truncation of it still is its byte order mark
+	if len(part) > len(bom) || (maxParts == 1 && len(parts) == 0) {
+		parts = append(parts, part)
+	}
+
+	return parts, true, nil
+}
+
+// returns codepoints encoded in targetEncoding and cut into parts of at most maxBytes bytes, at grapheme cluster boundaries
+func Split(codepoints []uint32, targetEncoding string, options Options, maxBytes int) ([][]byte, error) {
+	parts, _, err := split(codepoints, targetEncoding, options, maxBytes, 0)
+	return parts, err
+}
+
+// returns the longest run of whole grapheme clusters from the start of codepoints which fits into maxBytes bytes
+// in targetEncoding, and whether anything was cut off. a cluster larger than maxBytes cuts off the rest with it
+func Truncate(codepoints []uint32, targetEncoding string, options Options, maxBytes int) ([]byte, bool, error) {
+	parts, complete, err := split(codepoints, targetEncoding, options, maxBytes, 1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return parts[0], !complete, nil
+}
@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+	"utfcoder/types"
+)
+
+// "ab", e + combining acute, 😀 (U+1F600)
+var splitTestCodepoints = []uint32{'a', 'b', 'e', 0x301, 0x1F600}
+
+func TestSplit(t *testing.T) {
+	for _, test := range splitTestInputs {
+		parts, err := Split(splitTestCodepoints, test.encoding, Options{AddBOM: test.addBOM}, test.maxBytes)
+
+		if err != nil || len(parts) != len(test.expected) {
+			t.Errorf(`Split(%v, %v) = parts=%v, error=%v, Expected = parts=%v, error=%v`, test.encoding, test.maxBytes, parts, err, test.expected, nil)
+			continue
+		}
+		for idx := range parts {
+			if !bytes.Equal(parts[idx], test.expected[idx]) {
+				t.Errorf(`Split(%v, %v) = parts=%v, Expected = parts=%v`, test.encoding, test.maxBytes, parts, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestSplitClusterTooLarge(t *testing.T) {
+	if _, err := Split(splitTestCodepoints, types.UTF_16LE, Options{}, 3); err == nil {
+		t.Errorf(`Split(%v, 3) = error=%v, Expected = error=%v`, types.UTF_16LE, err, "grapheme cluster too large")
+	}
+}
+
+func TestSplitEmpty(t *testing.T) {
+	// empty text is written as no part at all, with or without a byte order mark
+	for _, addBOM := range []bool{false, true} {
+		if parts, err := Split(nil, types.UTF_16LE, Options{AddBOM: addBOM}, 4); len(parts) != 0 || err != nil {
+			t.Errorf(`Split(nil, bom=%v) = parts=%v, error=%v, Expected = parts=[], error=%v`, addBOM, parts, err, nil)
+		}
+	}
+
+	if parts, err := Split(splitTestCodepoints, types.UTF_32LE, Options{AddBOM: true}, 3); err == nil {
+		t.Errorf(`Split(%v, 3) = parts=%v, error=%v, Expected = error=%v`, types.UTF_32LE, parts, err, "byte order mark too large")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	output, truncated, err := Truncate(splitTestCodepoints, types.UTF_8, Options{}, 5)
+	if !bytes.Equal(output, []byte{'a', 'b', 'e', 0xCC, 0x81}) || !truncated || err != nil {
+		t.Errorf(`Truncate(5) = output=%v, truncated=%v, error=%v, Expected = output=%v, truncated=%v, error=%v`, output, truncated, err, "abé", true, nil)
+	}
+
+	output, truncated, err = Truncate(splitTestCodepoints, types.UTF_16LE, Options{}, 100)
+	if len(output) != 12 || truncated || err != nil {
+		t.Errorf(`Truncate(100) = output=%v, truncated=%v, error=%v, Expected = length=%v, truncated=%v, error=%v`, output, truncated, err, 12, false, nil)
+	}
+
+	// the family emoji is a single cluster of 18 bytes, longer than the limit
+	family := []uint32{'a', 'b', 0x1F468, 0x200D, 0x1F469, 0x200D, 0x1F467, 'c'}
+	output, truncated, err = Truncate(family, types.UTF_8, Options{}, 3)
+	if !bytes.Equal(output, []byte("ab")) || !truncated || err != nil {
+		t.Errorf(`Truncate(3) = output=%v, truncated=%v, error=%v, Expected = output=%v, truncated=%v, error=%v`, output, truncated, err, "ab", true, nil)
+	}
+
+	// no output holds even the byte order mark
+	if output, _, err = Truncate(family, types.UTF_16LE, Options{AddBOM: true}, 1); err == nil {
+		t.Errorf(`Truncate(1) = output=%v, error=%v, Expected = error=%v`, output, err, "byte order mark too large")
+	}
+
+	output, truncated, err = Truncate(nil, types.UTF_8, Options{AddBOM: true}, 3)
+	if !bytes.Equal(output, []byte{0xEF, 0xBB, 0xBF}) || truncated || err != nil {
+		t.Errorf(`Truncate(nil, 3) = output=%v, truncated=%v, error=%v, Expected = output=%v, truncated=%v, error=%v`, output, truncated, err, []byte{0xEF, 0xBB, 0xBF}, false, nil)
+	}
+}
+
+var splitTestInputs = []struct {
+	encoding string
+	addBOM   bool
+	maxBytes int
+	expected [][]byte
+}{
+	{types.UTF_8, false, 4, [][]byte{{'a', 'b'}, {'e', 0xCC, 0x81}, {0xF0, 0x9F, 0x98, 0x80}}},
+	{types.UTF_8, true, 7, [][]byte{{0xEF, 0xBB, 0xBF, 'a', 'b'}, {0xEF, 0xBB, 0xBF, 'e', 0xCC, 0x81}, {0xEF, 0xBB, 0xBF, 0xF0, 0x9F, 0x98, 0x80}}},
+	{types.UTF_16LE, false, 8, [][]byte{{'a', 0, 'b', 0, 'e', 0, 0x01, 0x03}, {0x3D, 0xD8, 0x00, 0xDE}}},
+	{types.UTF_8, false, 100, [][]byte{{'a', 'b', 'e', 0xCC, 0x81, 0xF0, 0x9F, 0x98, 0x80}}},
+}
@@ -0,0 +1,68 @@
+package codec
+
+import (
+	"utfcoder/types"
+	UTF16 "utfcoder/utf16"
+	UTF32 "utfcoder/utf32"
+	"utfcoder/utils"
+)
+
+// returns the byte order a stream of sourceEncoding has, from its first bytes. utf-16 and utf-32 are told apart the
+// way Decode tells them, so a stream is read like the whole file would be. read in parts, every part has to be decoded
+// with the same byte order
+func StreamEncoding(first []byte, sourceEncoding string) string {
+	switch sourceEncoding {
+	case types.UTF_16:
+		return sourceEncoding + string(UTF16.ByteOrder(first))
+	case types.UTF_32:
+		return sourceEncoding + string(UTF32.ByteOrder(first))
+	}
+	return sourceEncoding
+}
+
+// returns the length of the longest prefix of input which doesn't end in the middle of a code point, the rest has to
+// wait for the bytes which follow it. a high surrogate waits for its low surrogate
+func Complete(input []byte, sourceEncoding string) int {
+	switch sourceEncoding {
+	case types.UTF_8:
+		// the lead byte of the last sequence is at most 3 bytes from the end
+		for idx := len(input) - 1; idx >= 0 && idx >= len(input)-3; idx -= 1 {
+			b := input[idx]
+			if b&0xC0 == 0x80 {
+				continue
+			}
+
+			length := 1
+			switch {
+			case b >= 0xF8:
+				// not a lead byte, it is reported as it is
+			case b >= 0xF0:
+				length = 4
+			case b >= 0xE0:
+				length = 3
+			case b >= 0xC0:
+				length = 2
+			}
+			if idx+length > len(input) {
+				return idx
+			}
+			break
+		}
+		return len(input)
+	case types.UTF_16, types.UTF_16LE, types.UTF_16BE:
+		end := len(input) &^ 1
+		if end >= 2 {
+			unit := uint32(input[end-2])<<8 | uint32(input[end-1])
+			if !utils.IsBigEndian(sourceEncoding) {
+				unit = uint32(input[end-1])<<8 | uint32(input[end-2])
+			}
+			if unit >= 0xD800 && unit <= 0xDBFF {
+				return end - 2
+			}
+		}
+		return end
+	case types.UTF_32, types.UTF_32LE, types.UTF_32BE:
+		return len(input) &^ 3
+	}
+	return len(input)
+}
@@ -0,0 +1,65 @@
+package codec
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+func TestComplete(t *testing.T) {
+	for _, test := range completeTestInputs {
+		length := Complete(test.input, test.sourceEncoding)
+
+		if length != test.length {
+			t.Errorf(`Complete(%v, %v) = length=%v, Expected = length=%v`, test.input, test.sourceEncoding, length, test.length)
+		}
+	}
+}
+
+func TestStreamEncoding(t *testing.T) {
+	for _, test := range streamEncodingTestInputs {
+		encoding := StreamEncoding(test.first, test.sourceEncoding)
+
+		if encoding != test.encoding {
+			t.Errorf(`StreamEncoding(%v, %v) = encoding=%v, Expected = encoding=%v`, test.first, test.sourceEncoding, encoding, test.encoding)
+		}
+	}
+}
+
+var completeTestInputs = []struct {
+	input          []byte
+	sourceEncoding string
+	length         int
+}{
+	{[]byte("abc"), types.UTF_8, 3},
+	{[]byte{'a', 0xE2, 0x80}, types.UTF_8, 1},
+	{[]byte{'a', 0xE2, 0x80, 0x99}, types.UTF_8, 4},
+	{[]byte{0xF0, 0x9F, 0x98}, types.UTF_8, 0},
+	{[]byte{'a', 0xC3}, types.UTF_8, 1},
+	// stray continuation bytes and invalid bytes wait for nothing
+	{[]byte{'a', 0x80, 0x80, 0x80, 0x80}, types.UTF_8, 5},
+	{[]byte{'a', 0xFF}, types.UTF_8, 2},
+	{[]byte{'h', 0x00, 'i'}, types.UTF_16LE, 2},
+	{[]byte{'h', 0x00, 0x3D, 0xD8}, types.UTF_16LE, 2},
+	{[]byte{'h', 0x00, 0x3D, 0xD8, 0x00, 0xDE}, types.UTF_16LE, 6},
+	{[]byte{0x00, 'h', 0xD8, 0x3D, 0xDE}, types.UTF_16BE, 2},
+	{[]byte{'h', 0x00, 0x00, 0x00, 'i', 0x00}, types.UTF_32LE, 4},
+}
+
+var streamEncodingTestInputs = []struct {
+	first          []byte
+	sourceEncoding string
+	encoding       string
+}{
+	{[]byte{0xFF, 0xFE, 'h', 0x00}, types.UTF_16, types.UTF_16LE},
+	// without a byte order mark utf-16 is big endian unless a surrogate tells otherwise, utf-32 little endian unless
+	// its high bytes do, like Decode reads them
+	{[]byte{'h', 0x00, 'i', 0x00}, types.UTF_16, types.UTF_16BE},
+	{[]byte{0x00, 0xD8, 0x00, 0xDC}, types.UTF_16, types.UTF_16LE},
+	{[]byte{0x00, 0xF6, 0x01, 0x00}, types.UTF_32, types.UTF_32LE},
+	{[]byte{0x00, 0x01, 0xF6, 0x00}, types.UTF_32, types.UTF_32BE},
+	{[]byte{0xFE, 0xFF, 0x00, 'h'}, types.UTF_16, types.UTF_16BE},
+	{[]byte{0xFF, 0xFE, 0x00, 0x00}, types.UTF_32, types.UTF_32LE},
+	{[]byte{0x12, 0x34}, types.UTF_16, types.UTF_16BE},
+	{[]byte("abc"), types.UTF_8, types.UTF_8},
+	{[]byte{'h', 0x00}, types.UTF_16LE, types.UTF_16LE},
+}
@@ -0,0 +1,340 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"sync"
+	"unicode"
+	"utfcoder/types"
+	UTF16 "utfcoder/utf16"
+	UTF32 "utfcoder/utf32"
+	UTF8 "utfcoder/utf8"
+	"utfcoder/utils"
+)
+
+// the encodings FindStrings looks for runs of, in the order ties between overlapping runs are settled in
+var StringEncodings = []string{types.UTF_8, types.UTF_16LE, types.UTF_16BE, types.UTF_32LE, types.UTF_32BE}
+
+// TextRun is a run of accepted code points found in binary input
+type TextRun struct {
+	Offset, Size int
+	Encoding     string
+	Codepoints   []uint32
+}
+
+// returns the code point at input[i] in encoding and its size in bytes, size 0 when input[i] doesn't start a valid one
+func decodeAt(input []byte, i int, encoding string) (uint32, int) {
+	switch encoding {
+	case types.UTF_16LE, types.UTF_16BE:
+		return UTF16.DecodeAt(input, i, utils.IsBigEndian(encoding))
+	case types.UTF_32LE, types.UTF_32BE:
+		return UTF32.DecodeAt(input, i, utils.IsBigEndian(encoding))
+	}
+	return UTF8.DecodeAt(input, i)
+}
+
+// the size of the windows FindStrings reads its input in. a string which goes on for a whole window past the end of the
+// last one is cut there, so no more than about two windows are ever held
+var stringWindowSize = 1 << 20
+
+// the numbers standing for the script of every code point, see scriptOf
+var (
+	scriptIDs     []uint8
+	scriptIDsOnce sync.Once
+)
+
+// returns a number standing for the script of bits, kana counted as han like the japanese text mixing them. 0 for the
+// code points common to every script
+func scriptOf(bits uint32) uint8 {
+	scriptIDsOnce.Do(func() {
+		ids := make([]uint8, unicode.MaxRune+1)
+		names := make([]string, 0, len(unicode.Scripts))
+		for name := range unicode.Scripts {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		next := uint8(2)
+		for _, name := range names {
+			id := next
+			switch name {
+			case "Common", "Inherited":
+				continue
+			case "Han", "Hiragana", "Katakana":
+				id = 1
+			default:
+				next += 1
+			}
+
+			table := unicode.Scripts[name]
+			for _, r := range table.R16 {
+				for c := int(r.Lo); c <= int(r.Hi); c += int(r.Stride) {
+					ids[c] = id
+				}
+			}
+			for _, r := range table.R32 {
+				for c := int(r.Lo); c <= int(r.Hi); c += int(r.Stride) {
+					ids[c] = id
+				}
+			}
+		}
+		scriptIDs = ids
+	})
+
+	if bits >= uint32(len(scriptIDs)) {
+		return 0
+	}
+	return scriptIDs[bits]
+}
+
+// a part of a run whose letters are of one script, competing with the parts of other encodings and alignments which
+// overlap it. offsets are from the start of the input
+type candidate struct {
+	offset, size int
+	encoding     string
+	// the bytes taken by the code points which look like text, and the place of encoding in the list searched
+	score, rank int
+}
+
+// orders the candidates kept first where they overlap
+func compareCandidates(a, b candidate) int {
+	if a.score != b.score {
+		return b.score - a.score
+	}
+	if isAligned, isOtherAligned := a.offset%UnitSize(a.encoding) == 0, b.offset%UnitSize(b.encoding) == 0; isAligned != isOtherAligned {
+		if isAligned {
+			return -1
+		}
+		return 1
+	}
+	if a.rank != b.rank {
+		return a.rank - b.rank
+	}
+	return a.offset - b.offset
+}
+
+// stringWindow is the part of the input FindStrings holds, from base on
+type stringWindow struct {
+	buf  []byte
+	base int
+	// whether runs end with buf, at the end of the input or where a string too long is cut
+	isLast bool
+	// the spans of the runs of at least minLength code points, and the candidates cut from them
+	runs       [][2]int
+	candidates []candidate
+}
+
+// stringFinder holds what FindStrings was asked for, and what accept answered for every code point asked about
+type stringFinder struct {
+	minLength       int
+	accept          func(uint32) bool
+	known, accepted []uint64
+}
+
+// returns whether accept takes bits, asking it once per code point
+func (f *stringFinder) accepts(bits uint32) bool {
+	if bits > unicode.MaxRune {
+		return f.accept(bits)
+	}
+
+	word, bit := bits/64, uint64(1)<<(bits%64)
+	if f.known[word]&bit == 0 {
+		f.known[word] |= bit
+		if f.accept(bits) {
+			f.accepted[word] |= bit
+		}
+	}
+	return f.accepted[word]&bit != 0
+}
+
+// adds the runs of at least minLength code points accept takes, of encoding, which start at an offset of alignment
+// modulo its code unit size, to w. returns the index of w.buf the scan has to start again from once more input is
+// read, where its last run starts or past where it got to, len(w.buf) when w is the last
+func (f *stringFinder) scan(w *stringWindow, encoding string, rank, alignment int) int {
+	start, count := 0, 0
+	end := func(offset int) {
+		if count >= f.minLength {
+			w.runs = append(w.runs, [2]int{w.base + start, w.base + offset})
+			f.cut(w, encoding, rank, start, offset)
+		}
+		count = 0
+	}
+
+	unitSize := UnitSize(encoding)
+	i := ((alignment-w.base)%unitSize + unitSize) % unitSize
+	for i < len(w.buf) {
+		// the code point may go on in the next window
+		if !w.isLast && len(w.buf)-i < 4 {
+			break
+		}
+
+		bits, size := decodeAt(w.buf, i, encoding)
+		if size == 0 || !f.accepts(bits) {
+			end(i)
+			i += unitSize
+			continue
+		}
+
+		if count == 0 {
+			start = i
+		}
+		count += 1
+		i += size
+	}
+
+	if !w.isLast {
+		if count > 0 {
+			return start
+		}
+		return min(i, len(w.buf))
+	}
+	end(min(i, len(w.buf)))
+	return len(w.buf)
+}
+
+// adds the parts of the run w.buf[from:to] as candidates, cut where its letters change script, the code points common
+// to every script staying with the letters before them. junk read in the wrong encoding or alignment changes script
+// all the time, and text next to it keeps its own part. a part is scored by the code points which look like text:
+// ascii read in utf-16 or utf-32 in the wrong alignment reads as code points whose low byte is 0, which don't count
+func (f *stringFinder) cut(w *stringWindow, encoding string, rank int, from, to int) {
+	part := candidate{offset: w.base + from, encoding: encoding, rank: rank}
+	count := 0
+	var current uint8
+
+	for i := from; i < to; {
+		bits, size := decodeAt(w.buf, i, encoding)
+		if script := scriptOf(bits); script != 0 {
+			if current != 0 && script != current {
+				if count >= f.minLength {
+					w.candidates = append(w.candidates, part)
+				}
+				part, count = candidate{offset: w.base + i, encoding: encoding, rank: rank}, 0
+			}
+			current = script
+		}
+
+		part.size += size
+		if UnitSize(encoding) == 1 || bits < 0x100 || bits >= 0x10000 || bits&0xFF != 0 {
+			count += 1
+			part.score += size
+		}
+		i += size
+	}
+
+	if count >= f.minLength {
+		w.candidates = append(w.candidates, part)
+	}
+}
+
+// returns the index of w.buf from which on its runs have to wait for more input: until, where the first scan has to
+// start again, or the start of the runs overlapping each other up to past it
+func (w *stringWindow) carryFrom(until int) int {
+	slices.SortFunc(w.runs, func(a, b [2]int) int { return a[0] - b[0] })
+
+	for idx := 0; idx < len(w.runs); {
+		start, end := w.runs[idx][0], w.runs[idx][1]
+		for idx += 1; idx < len(w.runs) && w.runs[idx][0] < end; idx += 1 {
+			end = max(end, w.runs[idx][1])
+		}
+		if end > w.base+until {
+			return min(until, start-w.base)
+		}
+	}
+	return until
+}
+
+// emits the candidates of w starting before offset until which are kept: where they overlap, the first by
+// compareCandidates. candidates overlapping each other are settled apart from the rest, which none of them touches
+func (w *stringWindow) resolve(until int, emit func(TextRun) error) error {
+	slices.SortFunc(w.candidates, func(a, b candidate) int { return a.offset - b.offset })
+
+	var kept []candidate
+	for idx := 0; idx < len(w.candidates) && w.candidates[idx].offset < until; {
+		group := idx
+		end := w.candidates[idx].offset + w.candidates[idx].size
+		for idx += 1; idx < len(w.candidates) && w.candidates[idx].offset < end; idx += 1 {
+			end = max(end, w.candidates[idx].offset+w.candidates[idx].size)
+		}
+
+		overlapping := w.candidates[group:idx]
+		slices.SortStableFunc(overlapping, compareCandidates)
+		kept = kept[:0]
+		for _, c := range overlapping {
+			// kept is sorted by offset and its candidates don't overlap, only the last starting before c ends may
+			at, _ := slices.BinarySearchFunc(kept, c.offset+c.size, func(k candidate, offset int) int { return k.offset - offset })
+			if at > 0 && kept[at-1].offset+kept[at-1].size > c.offset {
+				continue
+			}
+			kept = slices.Insert(kept, at, c)
+		}
+
+		for _, c := range kept {
+			run := TextRun{Offset: c.offset, Size: c.size, Encoding: c.encoding}
+			for i := c.offset - w.base; i < c.offset-w.base+c.size; {
+				bits, size := decodeAt(w.buf, i, c.encoding)
+				run.Codepoints = append(run.Codepoints, bits)
+				i += size
+			}
+			if err := emit(run); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// emits the runs of at least minLength code points accept takes, in every alignment of every encoding, by offset.
+// where runs overlap, which every byte sequence read in another encoding or alignment does, only the one whose code
+// points which look like text take the most bytes is kept. when they tie, a run at an offset aligned to its code unit
+// size wins, like the strings compilers and file formats write, then the encoding listed first in encodings. runs with
+// fewer than minLength code points which look like text are dropped. r is read a window at a time, and the runs
+// overlapping each other are settled as soon as none of them can go on in the next window
+func FindStrings(r io.Reader, encodings []string, minLength int, accept func(uint32) bool, emit func(TextRun) error) error {
+	f := &stringFinder{
+		minLength: minLength,
+		accept:    accept,
+		known:     make([]uint64, unicode.MaxRune/64+1),
+		accepted:  make([]uint64, unicode.MaxRune/64+1),
+	}
+	chunk := make([]byte, stringWindowSize)
+
+	var w stringWindow
+	for isEnd := false; !isEnd; {
+		carried := len(w.buf)
+		n, err := io.ReadFull(r, chunk)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			isEnd = true
+		} else if err != nil {
+			return err
+		}
+		w.buf = append(w.buf, chunk[:n]...)
+		w.isLast = isEnd || carried >= stringWindowSize
+
+		w.runs, w.candidates = w.runs[:0], w.candidates[:0]
+		until := len(w.buf)
+		for rank, encoding := range encodings {
+			for alignment := 0; alignment < UnitSize(encoding); alignment += 1 {
+				until = min(until, f.scan(&w, encoding, rank, alignment))
+			}
+		}
+
+		from := w.carryFrom(until)
+		if err := w.resolve(w.base+from, emit); err != nil {
+			return err
+		}
+		w.buf = append(w.buf[:0], w.buf[from:]...)
+		w.base += from
+	}
+	return nil
+}
+
+// returns the runs FindStrings finds in input
+func Strings(input []byte, encodings []string, minLength int, accept func(uint32) bool) []TextRun {
+	var runs []TextRun
+	FindStrings(bytes.NewReader(input), encodings, minLength, accept, func(run TextRun) error {
+		runs = append(runs, run)
+		return nil
+	})
+	return runs
+}
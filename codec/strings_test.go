@@ -0,0 +1,69 @@
+package codec
+
+import (
+	"slices"
+	"testing"
+	"unicode"
+	"utfcoder/types"
+)
+
+func TestStrings(t *testing.T) {
+	isGraphic := func(bits uint32) bool { return unicode.IsGraphic(rune(bits)) }
+
+	for _, test := range stringsTestInputs {
+		runs := Strings(test.input, StringEncodings, 4, isGraphic)
+
+		var found []string
+		for _, run := range runs {
+			var text []rune
+			for _, bits := range run.Codepoints {
+				text = append(text, rune(bits))
+			}
+			found = append(found, run.Encoding+" "+string(text))
+		}
+
+		if !slices.Equal(found, test.found) {
+			t.Errorf(`Strings(%v) = found=%q, Expected = found=%q`, test.input, found, test.found)
+		}
+	}
+}
+
+func TestStringsWindows(t *testing.T) {
+	isGraphic := func(bits uint32) bool { return unicode.IsGraphic(rune(bits)) }
+
+	// the strings of every test over and over, at every alignment, some of them going on past the end of a window. the
+	// zeros between them keep the runs overlapping each other shorter than a window, which would be cut
+	var input []byte
+	for repeat := 0; repeat < 8; repeat += 1 {
+		for _, test := range stringsTestInputs {
+			input = append(append(input, test.input...), make([]byte, 8+repeat%4)...)
+		}
+	}
+	whole := Strings(input, StringEncodings, 4, isGraphic)
+
+	defer func(size int) { stringWindowSize = size }(stringWindowSize)
+	for _, size := range []int{33, 47, 64} {
+		stringWindowSize = size
+		runs := Strings(input, StringEncodings, 4, isGraphic)
+		if !slices.EqualFunc(runs, whole, func(a, b TextRun) bool {
+			return a.Offset == b.Offset && a.Size == b.Size && a.Encoding == b.Encoding && slices.Equal(a.Codepoints, b.Codepoints)
+		}) {
+			t.Errorf(`Strings(window=%v) = runs=%v, Expected = runs=%v`, size, runs, whole)
+		}
+	}
+}
+
+var stringsTestInputs = []struct {
+	input []byte
+	found []string
+}{
+	{[]byte("\x00\x01Hello\x00\x02"), []string{types.UTF_8 + " Hello"}},
+	// ascii read as utf-16 doesn't look like text, but a surrogate pair does
+	{[]byte{0x00, 0x00, 'M', 0x00, 0xFC, 0x00, 'l', 0x00, 'l', 0x00, 0x3D, 0xD8, 0x00, 0xDE, 0x00, 0x00}, []string{types.UTF_16LE + " Müll😀"}},
+	{[]byte{0x04, 0x1F, 0x04, 0x40, 0x04, 0x38, 0x04, 0x32, 0x00, 0x00}, []string{types.UTF_16BE + " Прив"}},
+	{[]byte{'t', 0x00, 0x00, 0x00, 'e', 0x00, 0x00, 0x00, 'x', 0x00, 0x00, 0x00, 't', 0x00, 0x00, 0x00}, []string{types.UTF_32LE + " text"}},
+	// a lone surrogate ends a run
+	{[]byte{'a', 0x00, 'b', 0x00, 0x3D, 0xD8, 'c', 0x00, 'd', 0x00, 'e', 0x00}, nil},
+	// han in utf-16le, whose bytes read as utf-8 are ascii
+	{[]byte{0x48, 0x72, 0x2C, 0x67, 0xE1, 0x4F, 0x6F, 0x60, 0x00, 0x00}, []string{types.UTF_16LE + " 版本信息"}},
+}
@@ -0,0 +1,152 @@
+package codec
+
+import (
+	"fmt"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+// number of differences a Verifier keeps, a code point lost or split shifts every one after it
+const maxDifferences = 10
+
+// Difference is where the output of a conversion doesn't decode back to the text which was encoded
+type Difference struct {
+	// index of the code point in the text, and its 1-based line and column
+	Index, Line, Column int
+	Reason              string
+}
+
+// VerificationError is returned when the output doesn't decode back to the text, it keeps the first differences
+type VerificationError struct {
+	Differences []Difference
+	Count       int
+}
+
+func (e *VerificationError) Error() string {
+	first := e.Differences[0]
+	return fmt.Sprintf("output doesn't decode back to the text, %v difference(s), the first at code point %v (line %v, column %v): %v",
+		e.Count, first.Index, first.Line, first.Column, first.Reason)
+}
+
+// Verifier decodes the output of a conversion back and checks it against the text which was encoded. the text and
+// the output are given in parts of any length, so a stream is checked without holding all of it
+type Verifier struct {
+	targetEncoding string
+	options        Options
+	// code points of the text the output hasn't reached yet
+	expected []uint32
+	// where the first of them is in the text
+	index    int
+	position utils.Position
+	// output has been written to the file
+	isStarted   bool
+	differences []Difference
+	count       int
+}
+
+func NewVerifier(targetEncoding string, options Options) *Verifier {
+	return &Verifier{targetEncoding: targetEncoding, options: options, position: utils.NewPosition()}
+}
+
+func (v *Verifier) add(reason string) {
+	if len(v.differences) < maxDifferences {
+		v.differences = append(v.differences, Difference{Index: v.index, Line: v.position.Line, Column: v.position.Column, Reason: reason})
+	}
+	v.count += 1
+}
+
+// returns "U+00E9" or "undecodable byte E9" for a code point
+func describe(bits uint32) string {
+	if utils.IsEscapedByte(bits) {
+		return fmt.Sprintf("undecodable byte %02X", byte(bits))
+	}
+	return fmt.Sprintf("U+%04X", bits)
+}
+
+// adds codepoints to the text the output has to decode back to
+func (v *Verifier) Expect(codepoints []uint32) {
+	v.expected = append(v.expected, codepoints...)
+}
+
+// the output which follows is a file of its own, which starts with a byte order mark of its own
+func (v *Verifier) StartFile() {
+	v.isStarted = false
+}
+
+// decodes output, the next part of the encoded text, and checks it against the text expected
+func (v *Verifier) Write(output []byte) error {
+	if IsUTF(v.targetEncoding) {
+		hasBOM := BOMLength(output, v.targetEncoding) > 0
+		if !v.isStarted && v.options.AddBOM && !hasBOM {
+			v.add("byte order mark missing")
+		}
+		// the decoders skip a byte order mark, a U+FEFF at the start of the part is text which is kept behind a mark
+		// of its own. this also fixes the byte order of utf-16 and utf-32 without one
+		if v.isStarted || !v.options.AddBOM || !hasBOM {
+			output = append(utils.AppendBOM(nil, v.targetEncoding), output...)
+		}
+	}
+	v.isStarted = true
+
+	// undecodable bytes the output carries come back as the bytes they are
+	decoded, _, err := Decode(output, v.targetEncoding, Options{ErrorMode: types.SURROGATE_ESCAPE, Charset: v.options.Charset})
+	if err != nil {
+		return err
+	}
+
+	for _, bits := range decoded {
+		if len(v.expected) == 0 {
+			v.add(fmt.Sprintf("%v in the output is not in the text", describe(bits)))
+			continue
+		}
+
+		if bits != v.expected[0] {
+			v.add(fmt.Sprintf("%v came back as %v", describe(v.expected[0]), describe(bits)))
+		}
+		v.index += 1
+		v.position.Advance(v.expected[0])
+		v.expected = v.expected[1:]
+	}
+	return nil
+}
+
+// checks that the output reached the end of the text, returns a *VerificationError when there were differences
+func (v *Verifier) Close() error {
+	if len(v.expected) != 0 {
+		v.add(fmt.Sprintf("%v and %v code point(s) after it missing from the output", describe(v.expected[0]), len(v.expected)-1))
+		v.expected = nil
+	}
+	return v.Err()
+}
+
+// returns a *VerificationError when there were differences so far
+func (v *Verifier) Err() error {
+	if v.count == 0 {
+		return nil
+	}
+	return &VerificationError{Differences: v.differences, Count: v.count}
+}
+
+// returns the text the output has to decode back to: the input decoded with the bytes replaced by U+FFFD kept as the
+// bytes they are, so a replacement counts as a difference
+func Expected(input []byte, sourceEncoding string, codepoints []uint32, options Options) ([]uint32, error) {
+	if options.ErrorMode == types.SURROGATE_ESCAPE {
+		return codepoints, nil
+	}
+
+	options.ErrorMode = types.SURROGATE_ESCAPE
+	expected, _, err := Decode(input, sourceEncoding, options)
+	if err != nil {
+		return nil, err
+	}
+	return Apply(expected, options, &Report{}), nil
+}
+
+// returns whether encoding is utf-8, utf-16 or utf-32, the encodings with a byte order mark
+func IsUTF(encoding string) bool {
+	switch encoding {
+	case types.UTF_8, types.UTF_16, types.UTF_16LE, types.UTF_16BE, types.UTF_32, types.UTF_32LE, types.UTF_32BE:
+		return true
+	}
+	return false
+}
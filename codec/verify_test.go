@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+	"utfcoder/types"
+)
+
+func TestVerifier(t *testing.T) {
+	for _, test := range verifierTestInputs {
+		v := NewVerifier(test.targetEncoding, Options{AddBOM: test.addBOM})
+		v.Expect(test.codepoints)
+		for _, part := range test.parts {
+			if err := v.Write(part); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var reasons []string
+		var verification *VerificationError
+		if err := v.Close(); errors.As(err, &verification) {
+			for _, difference := range verification.Differences {
+				reasons = append(reasons, difference.Reason)
+			}
+		}
+
+		if len(reasons) != len(test.reasons) {
+			t.Errorf(`Verifier(%v, %v) = reasons=%q, Expected = reasons=%q`, test.codepoints, test.parts, reasons, test.reasons)
+			continue
+		}
+		for idx := range reasons {
+			if reasons[idx] != test.reasons[idx] {
+				t.Errorf(`Verifier(%v, %v) = reasons=%q, Expected = reasons=%q`, test.codepoints, test.parts, reasons, test.reasons)
+				break
+			}
+		}
+	}
+}
+
+func TestConvertVerify(t *testing.T) {
+	for _, test := range convertVerifyTestInputs {
+		_, _, err := Convert(test.input, test.sourceEncoding, test.targetEncoding, Options{ErrorMode: test.errorMode, Verify: true})
+
+		var verification *VerificationError
+		if isDifferent := errors.As(err, &verification); isDifferent != test.isDifferent {
+			t.Errorf(`Convert(%v, %v, %v, verify) = err=%v, Expected = different=%v`, test.input, test.sourceEncoding, test.targetEncoding, err, test.isDifferent)
+		}
+	}
+}
+
+var verifierTestInputs = []struct {
+	codepoints     []uint32
+	targetEncoding string
+	addBOM         bool
+	parts          [][]byte
+	reasons        []string
+}{
+	{[]uint32{'h', 'i'}, types.UTF_8, false, [][]byte{[]byte("hi")}, nil},
+	// the output of a stream, a U+FEFF which starts a later part is text
+	{[]uint32{'h', 0xFEFF, 'i'}, types.UTF_8, false, [][]byte{[]byte("h"), []byte("\ufeffi")}, nil},
+	{[]uint32{0x1F600}, types.UTF_16LE, true, [][]byte{{0xFF, 0xFE, 0x3D, 0xD8, 0x00, 0xDE}}, nil},
+	// the surrogates of an astral code point written in the wrong order
+	{[]uint32{0x1F600}, types.UTF_16BE, false, [][]byte{{0xDE, 0x00, 0xD8, 0x3D}}, []string{"U+1F600 came back as U+FFFD", "U+FFFD in the output is not in the text"}},
+	{[]uint32{'h', 'i'}, types.UTF_8, false, [][]byte{[]byte("h\ufffdi")}, []string{"U+0069 came back as U+FFFD", "U+0069 in the output is not in the text"}},
+	{[]uint32{'h', 'i'}, types.UTF_16LE, true, [][]byte{{'h', 0x00, 'i', 0x00}}, []string{"byte order mark missing"}},
+	{[]uint32{'h'}, types.UTF_8, false, [][]byte{[]byte("\ufeffh")}, []string{"U+0068 came back as U+FEFF", "U+0068 in the output is not in the text"}},
+	{[]uint32{'h', 'i', '!'}, types.UTF_32BE, false, [][]byte{{0x00, 0x00, 0x00, 'h'}}, []string{"U+0069 and 1 code point(s) after it missing from the output"}},
+	{[]uint32{0xDCE9}, types.UTF_8, false, [][]byte{{0xE9}}, nil},
+}
+
+var convertVerifyTestInputs = []struct {
+	input                          []byte
+	sourceEncoding, targetEncoding string
+	errorMode                      types.ErrorMode
+	isDifferent                    bool
+}{
+	{[]byte("h\xf0\x9f\x98\x80"), types.UTF_8, types.UTF_16LE, types.REPLACE, false},
+	{[]byte{0x00, 0x01, 0xF6, 0x00}, types.UTF_32BE, types.UTF_16, types.REPLACE, false},
+	// the byte replaced by U+FFFD is lost
+	{[]byte("a\xe9b"), types.UTF_8, types.UTF_32LE, types.REPLACE, true},
+	{[]byte("a\xe9b"), types.UTF_8, types.UTF_8, types.SURROGATE_ESCAPE, false},
+	{[]byte("\xe9t\xe9"), types.UTF_8, types.ESCAPED_PYTHON, types.SURROGATE_ESCAPE, false},
+}
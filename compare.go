@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"utfcoder/codec"
+	"utfcoder/types"
+	"utfcoder/ucd"
+	"utfcoder/utils"
+)
+
+// compared is a side of compare: the code points of a file and where they came from
+type compared struct {
+	path, encoding string
+	codepoints     []uint32
+	// the bytes of every code point, nil when the text was changed after decoding
+	spans []codec.Span
+}
+
+// returns the sha-256 of codepoints written as utf-32be, which is the same whatever encoding they were decoded from
+func codepointHash(codepoints []uint32) string {
+	hash := sha256.New()
+	var unit [4]byte
+	for _, bits := range codepoints {
+		unit[0], unit[1], unit[2], unit[3] = byte(bits>>24), byte(bits>>16), byte(bits>>8), byte(bits)
+		hash.Write(unit[:])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *cli) readCompared(path, fromEncoding string, isIgnoreBOM bool, options codec.Options) (compared, error) {
+	data, err := c.readSource(path)
+	if err != nil {
+		return compared{}, err
+	}
+
+	// undecodable bytes are compared as the bytes they are
+	codepoints, _, encoding, err := decodeSource(data, fromEncoding, codec.Options{ErrorMode: types.SURROGATE_ESCAPE})
+	if err != nil {
+		return compared{}, err
+	}
+
+	side := compared{path: path, encoding: encoding}
+	if len(options.EOL) == 0 && len(options.Normalize) == 0 {
+		if side.spans, err = codec.Spans(data, encoding, codepoints); err != nil {
+			return compared{}, err
+		}
+	}
+
+	// the byte order mark is compared like the text, unless it is ignored
+	if bomLength := codec.BOMLength(data, encoding); bomLength > 0 {
+		side.encoding += " with bom"
+		if !isIgnoreBOM {
+			codepoints = append([]uint32{0xFEFF}, codepoints...)
+			if side.spans != nil {
+				side.spans = append([]codec.Span{{Offset: 0, Size: bomLength}}, side.spans...)
+			}
+		}
+	}
+
+	side.codepoints = codec.Apply(codepoints, options, &codec.Report{})
+	return side, nil
+}
+
+// prints where the code point at idx of a side is, or that its text ended before it
+func printDifference(w io.Writer, side compared, idx int) {
+	position := utils.NewPosition()
+	for _, bits := range side.codepoints[:min(idx, len(side.codepoints))] {
+		position.Advance(bits)
+	}
+
+	offset := ""
+	if idx < len(side.spans) {
+		offset = fmt.Sprintf(", offset %v", side.spans[idx].Offset)
+	}
+
+	if idx >= len(side.codepoints) {
+		fmt.Fprintf(w, "  %v: code point %v (line %v, column %v): end of text\n", side.path, idx, position.Line, position.Column)
+		return
+	}
+
+	bits := side.codepoints[idx]
+	description := fmt.Sprintf("U+%04X %v", bits, ucd.Name(bits))
+	if utils.IsEscapedByte(bits) {
+		description = fmt.Sprintf("undecodable byte %02X", byte(bits))
+	}
+	fmt.Fprintf(w, "  %v: code point %v (line %v, column %v%v): %v\n", side.path, idx, position.Line, position.Column, offset, description)
+}
+
+func runCompare(c *cli, args []string) int {
+	var fromA, fromB string
+	var isIgnoreBOM, isIgnoreEOL, isIgnoreNormalization bool
+
+	fs := c.newFlagSet("compare")
+	lowerStringVar(fs, &fromA, "from-a", types.AUTO, "`encoding` of the first file, auto detects it")
+	lowerStringVar(fs, &fromB, "from-b", types.AUTO, "`encoding` of the second file, auto detects it")
+	fs.BoolVar(&isIgnoreBOM, "ignore-bom", false, "compares the text after the byte order marks only")
+	fs.BoolVar(&isIgnoreEOL, "ignore-eol", false, "compares every line end (CRLF, LF, CR, NEL, LS, PS) as LF")
+	fs.BoolVar(&isIgnoreNormalization, "ignore-normalization", false, "compares the text normalized to NFC")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 2 {
+		return c.fail(exitUsage, "no two files mentioned. use 'utfcoder compare file file', - reads stdin")
+	}
+
+	var options codec.Options
+	if isIgnoreEOL {
+		options.EOL = types.EOL_LF
+	}
+	if isIgnoreNormalization {
+		options.Normalize = types.NFC
+	}
+
+	a, err := c.readCompared(fs.Arg(0), fromA, isIgnoreBOM, options)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+	b, err := c.readCompared(fs.Arg(1), fromB, isIgnoreBOM, options)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	for _, side := range []compared{a, b} {
+		fmt.Fprintf(c.stdout, "%v: %v, %v code points, sha256 %v\n", side.path, side.encoding, len(side.codepoints), codepointHash(side.codepoints))
+	}
+
+	idx := 0
+	for idx < len(a.codepoints) && idx < len(b.codepoints) && a.codepoints[idx] == b.codepoints[idx] {
+		idx += 1
+	}
+	if idx == len(a.codepoints) && idx == len(b.codepoints) {
+		fmt.Fprintln(c.stdout, "equal")
+		return exitOK
+	}
+
+	fmt.Fprintln(c.stdout, "different, first at")
+	printDifference(c.stdout, a, idx)
+	printDifference(c.stdout, b, idx)
+	return exitInvalid
+}
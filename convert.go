@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"utfcoder/codec"
+	"utfcoder/logger"
+	"utfcoder/transform"
+	"utfcoder/types"
+)
+
+// config holds the flags of the commands which convert a file
+type config struct {
+	sourceFile, targetFile, fromEncoding, toEncoding string
+	backupSuffix                                     string
+	isInPlace, isFollow                              bool
+	addBOM, reportEscapes, isVerify                  bool
+	errorMode                                        types.ErrorMode
+	escapeScope                                      types.EscapeScope
+	charset, fallback                                string
+	xmlCheck                                         types.XMLCheck
+	normalizationForm                                types.NormalizationForm
+	lineEnding                                       types.LineEnding
+	maxBytes, maxUnits                               int
+	isSplit, isFixMojibake, isBatch                  bool
+}
+
+func (cfg *config) register(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.sourceFile, "s", "", "source `file` to read, - reads stdin")
+	fs.StringVar(&cfg.targetFile, "t", "", "target `file` to write, stdout when empty")
+	cfg.registerInPlace(fs)
+	fs.BoolVar(&cfg.isFollow, "follow", false, "keeps converting what is appended to the source file, a line at a time, like tail -F")
+	cfg.registerConversion(fs)
+}
+
+func (cfg *config) registerInPlace(fs *flag.FlagSet) {
+	fs.BoolVar(&cfg.isInPlace, "in-place", false, "writes the converted file over the source file")
+	fs.StringVar(&cfg.backupSuffix, "backup", "", "keeps the source file of -in-place as the source file name with `suffix`, like .orig")
+}
+
+// registers the flags of how a file is converted, -from defaults to what cfg holds
+func (cfg *config) registerConversion(fs *flag.FlagSet) {
+	fromUsage := "source file `encoding`"
+	if cfg.fromEncoding == types.AUTO {
+		fromUsage += ", auto detects it for every file"
+	}
+	lowerStringVar(fs, &cfg.fromEncoding, "from", cfg.fromEncoding, fromUsage)
+	lowerStringVar(fs, &cfg.toEncoding, "to", "", "target file `encoding`")
+
+	fs.BoolVar(&cfg.addBOM, "bom", false, "specifies whether to include or not include BOM prefix")
+
+	lowerStringVar(fs, &cfg.errorMode, "errors", types.REPLACE, "how undecodable bytes are handled, `mode` is one of replace/surrogateescape")
+	fs.BoolVar(&cfg.reportEscapes, "report-escapes", false, "prints the number of bytes carried as surrogate escapes")
+	fs.BoolVar(&cfg.isVerify, "verify", false, "decodes the output again and fails when it isn't the decoded source, a replaced byte included")
+	lowerStringVar(fs, &cfg.charset, "charset", types.ASCII, "`charset` html-entities/xml-charref targets write unescaped and sources are read in, one of ascii/latin-1")
+	lowerStringVar(fs, &cfg.fallback, "fallback", "", "decodes utf-8 sources line by line, lines which aren't utf-8 with `charset`, one of windows-1252/latin-1/shift_jis/utf-16le/utf-16be")
+	lowerStringVar(fs, &cfg.xmlCheck, "xml-check", "", "reports (flag) or removes (remove) code points XML 1.0 forbids, `check` is one of flag/remove")
+	lowerStringVar(fs, &cfg.normalizationForm, "normalize", "", "normalizes the text to `form`, one of nfc/nfd/nfkc/nfkd")
+	lowerStringVar(fs, &cfg.lineEnding, "eol", "", "rewrites every line end (CRLF, LF, CR, NEL, LS, PS) to `style`, one of lf/crlf/cr, keep only reports mixed line ends")
+	fs.IntVar(&cfg.maxBytes, "max-bytes", 0, "truncates (split: cuts) the output at a grapheme cluster boundary to at most this many bytes")
+	fs.IntVar(&cfg.maxUnits, "max-units", 0, "truncates (split: cuts) the output at a grapheme cluster boundary to at most this many code units of the target encoding")
+	lowerStringVar(fs, &cfg.escapeScope, "escape", types.NON_ASCII, "which code points the escaped-* targets escape, `scope` is one of nonascii/nonprintable")
+}
+
+// fills in what follows from the parsed flags
+func (cfg *config) resolve(fs *flag.FlagSet) error {
+	// batch takes its files and directories as arguments
+	if fs.NArg() > 0 && !cfg.isBatch {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	// mojibake is usually repaired in place, so the target encoding defaults to the source encoding
+	if cfg.isFixMojibake && len(cfg.toEncoding) == 0 {
+		cfg.toEncoding = cfg.fromEncoding
+	}
+
+	if maxUnits := cfg.maxUnits * codec.UnitSize(cfg.toEncoding); maxUnits > 0 && (cfg.maxBytes <= 0 || maxUnits < cfg.maxBytes) {
+		cfg.maxBytes = maxUnits
+	}
+	return nil
+}
+
+func (cfg *config) options() codec.Options {
+	return codec.Options{
+		AddBOM: cfg.addBOM, ErrorMode: cfg.errorMode, EscapeScope: cfg.escapeScope, Charset: cfg.charset, Fallback: cfg.fallback,
+		XMLCheck: cfg.xmlCheck, Normalize: cfg.normalizationForm, EOL: cfg.lineEnding, FixMojibake: cfg.isFixMojibake,
+		Verify: cfg.isVerify,
+	}
+}
+
+// returns whether the text itself is changed on the way, which is what makes converting to the same encoding useful
+func (cfg *config) hasTextStage() bool {
+	return cfg.isFixMojibake || len(cfg.fallback) != 0 || len(cfg.normalizationForm) != 0 || len(cfg.lineEnding) != 0 || len(cfg.xmlCheck) != 0
+}
+
+func runConvert(c *cli, args []string) int {
+	return convert(c, "convert", args, config{})
+}
+
+// split takes the flags of convert, but writes the output cut into numbered parts
+func runSplit(c *cli, args []string) int {
+	return convert(c, "split", args, config{isSplit: true})
+}
+
+// fix-mojibake takes the flags of convert, and undoes utf-8 misread as windows-1252 or latin-1 on the way
+func runFixMojibake(c *cli, args []string) int {
+	return convert(c, "fix-mojibake", args, config{isFixMojibake: true})
+}
+
+func convert(c *cli, name string, args []string, cfg config) int {
+	fs := c.newFlagSet(name)
+	cfg.register(fs)
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if err := cfg.resolve(fs); err != nil {
+		return c.fail(exitUsage, err)
+	}
+	if err := RunPrechecks(&cfg); err != nil {
+		return c.fail(exitUsage, err)
+	}
+
+	// a source overwritten by mistake is gone, so only -in-place may write over it
+	if len(cfg.targetFile) != 0 && isSameFile(cfg.sourceFile, cfg.targetFile) {
+		return c.fail(exitUsage, "target file is the source file. use '-in-place' to convert a file in place")
+	}
+
+	if cfg.isFollow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return c.follow(ctx, cfg)
+	}
+
+	data, err := c.readSource(cfg.sourceFile)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	if cfg.isSplit {
+		if err := c.splitFile(data, cfg); err != nil {
+			return c.failConversion(err)
+		}
+		return exitOK
+	}
+
+	options := cfg.options()
+	options.MaxBytes = cfg.maxBytes
+	output, report, err := codec.Convert(data, cfg.fromEncoding, cfg.toEncoding, options)
+	if err != nil {
+		return c.failConversion(err)
+	}
+
+	printReport(c.stderr, report, cfg)
+
+	if report.Truncated {
+		fmt.Fprintln(c.stderr, "output truncated to", len(output), "bytes")
+	}
+
+	if err := c.writeTarget(cfg, data, output); err != nil {
+		return c.fail(exitFailure, err)
+	}
+	return exitOK
+}
+
+// fails the run with err, printing every difference -verify kept first
+func (c *cli) failConversion(err error) int {
+	var verification *codec.VerificationError
+	if errors.As(err, &verification) {
+		for _, difference := range verification.Differences {
+			fmt.Fprintf(c.stderr, "code point %v (line %v, column %v): %v\n", difference.Index, difference.Line, difference.Column, difference.Reason)
+		}
+	}
+	return c.fail(exitFailure, err)
+}
+
+// writes output to the target file, over the source file with -in-place, or to stdout when there is no target file.
+// data is the source, kept as the backup of in place conversion
+func (c *cli) writeTarget(cfg config, data []byte, output []byte) error {
+	if cfg.isInPlace {
+		if len(cfg.backupSuffix) != 0 {
+			if err := writeFileAtomic(cfg.sourceFile+cfg.backupSuffix, data, cfg.sourceFile); err != nil {
+				return err
+			}
+		}
+		return writeFileAtomic(cfg.sourceFile, output, cfg.sourceFile)
+	}
+
+	if len(cfg.targetFile) == 0 {
+		_, err := c.stdout.Write(output)
+		return err
+	}
+
+	// the target takes the mode and modification time of the source file, stdin has none
+	attributesFrom := cfg.sourceFile
+	if attributesFrom == "-" {
+		attributesFrom = ""
+	}
+	return writeFileAtomic(cfg.targetFile, output, attributesFrom)
+}
+
+func printReport(w io.Writer, report codec.Report, cfg config) {
+	for _, malformed := range report.Malformed {
+		fmt.Fprintf(w, "offset %v (line %v, column %v): %v %q\n", malformed.Offset, malformed.Line, malformed.Column, malformed.Reason, malformed.Bytes)
+	}
+
+	for _, line := range report.FallbackLines {
+		fmt.Fprintf(w, "line %v: not utf-8, decoded as %v\n", line, cfg.fallback)
+	}
+
+	for _, rejected := range report.Rejected {
+		fmt.Fprintf(w, "code point %v (line %v, column %v): %v\n", rejected.Offset, rejected.Line, rejected.Column, rejected.Reason)
+	}
+
+	for _, repair := range report.Repairs {
+		fmt.Fprintf(w, "line %v: %v -> %v (%v)\n", repair.Line, text(repair.Before), text(repair.After), layerSummary(repair.Layers))
+	}
+
+	if transform.IsMixed(report.LineEndings) {
+		fmt.Fprintln(w, "mixed line endings in input:", lineEndingSummary(report.LineEndings))
+	}
+
+	if cfg.reportEscapes {
+		fmt.Fprintln(w, report.Escaped, "undecodable bytes escaped")
+	}
+}
+
+// returns "CRLF 3, LF 1" for the line end styles counted
+func lineEndingSummary(counts map[types.LineEnding]int) string {
+	var styles []string
+	for _, style := range transform.LineEndingStyles {
+		if count := counts[style]; count > 0 {
+			styles = append(styles, fmt.Sprint(strings.ToUpper(string(style)), " ", count))
+		}
+	}
+	return strings.Join(styles, ", ")
+}
+
+// returns codepoints as a printable go string, anything which isn't a unicode scalar value is quoted
+func text(codepoints []uint32) string {
+	var runes = make([]rune, 0, len(codepoints))
+	for _, bits := range codepoints {
+		runes = append(runes, rune(bits))
+	}
+	return strconv.Quote(string(runes))
+}
+
+// returns "windows-1252, 1 layer" or "windows-1252 > latin-1, 2 layers"
+func layerSummary(layers []string) string {
+	if len(layers) == 1 {
+		return layers[0] + ", 1 layer"
+	}
+	return fmt.Sprint(strings.Join(layers, " > "), ", ", len(layers), " layers")
+}
+
+// writes the converted data cut into parts named after the target (or the source when there is no target)
+// as name.001, name.002, ...
+func (c *cli) splitFile(data []byte, cfg config) error {
+	prefix := cfg.targetFile
+	if len(prefix) == 0 {
+		prefix = cfg.sourceFile
+	}
+	if prefix == "-" {
+		prefix = "stdin"
+	}
+
+	options := cfg.options()
+	codepoints, report, err := codec.Decode(data, cfg.fromEncoding, options)
+	if err != nil {
+		return err
+	}
+
+	codepoints = codec.Apply(codepoints, options, &report)
+	printReport(c.stderr, report, cfg)
+
+	parts, err := codec.Split(codepoints, cfg.toEncoding, options, cfg.maxBytes)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		logger.Log("No text to split, no parts written")
+		return nil
+	}
+
+	// every part is a file of its own, the parts are checked before any is written
+	if cfg.isVerify {
+		expected, err := codec.Expected(data, cfg.fromEncoding, codepoints, options)
+		if err != nil {
+			return err
+		}
+		v := codec.NewVerifier(cfg.toEncoding, options)
+		v.Expect(expected)
+		for _, part := range parts {
+			v.StartFile()
+			if err := v.Write(part); err != nil {
+				return err
+			}
+		}
+		if err := v.Close(); err != nil {
+			return err
+		}
+	}
+
+	for idx, part := range parts {
+		partPath := fmt.Sprintf("%v.%03d", prefix, idx+1)
+		if err := writeFileAtomic(partPath, part, ""); err != nil {
+			return err
+		}
+		logger.Log("Written", len(part), "bytes to", partPath)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package corpus
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"utfcoder/codec"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+// the kinds of defects a corpus may hold
+const (
+	// ascii written in 2, 3 or 4 bytes of utf-8
+	OVERLONG string = "overlong"
+	// a surrogate which isn't part of a pair
+	LONE_SURROGATE string = "lone-surrogate"
+	// a value beyond U+10FFFF
+	BEYOND_MAX string = "beyond-max"
+	// the first bytes of a code point cut off by the end of the file
+	TRUNCATED_TAIL string = "truncated-tail"
+	// the byte order mark of the other byte order
+	WRONG_ENDIAN_BOM string = "wrong-endian-bom"
+)
+
+type Options struct {
+	// number of code points to generate, line ends and combining marks included
+	Length int
+	// relative weights of printable ascii, of the rest of the basic multilingual plane and of the code points beyond
+	// it, which utf-16 writes as surrogate pairs
+	ASCII, BMP, Astral float64
+	// chance of a character to be followed by 1 to 3 combining marks
+	Combining float64
+	// a line end follows every this many code points, none when 0
+	LineLength int
+	// prefix the output with the byte order mark of the encoding
+	AddBOM bool
+	// chance of a defect of each kind before every code point
+	Overlong, LoneSurrogates, BeyondMax float64
+	// end the output with a truncated code point
+	TruncatedTail bool
+	// prefix the output with the byte order mark of the other byte order
+	WrongEndianBOM bool
+}
+
+// Defect is a malformed sequence written on purpose
+type Defect struct {
+	Kind string `json:"kind"`
+	// byte offset of the sequence in the output
+	Offset int `json:"offset"`
+	// the bytes of the sequence in hex
+	Bytes string `json:"bytes"`
+}
+
+// Manifest describes a generated corpus, it is written next to it
+type Manifest struct {
+	Encoding string `json:"encoding"`
+	// seed which generates the same corpus again with the same options
+	Seed uint64 `json:"seed"`
+	// size of the output in bytes
+	Size int `json:"size"`
+	// number of code points written, and how many of them are of each kind
+	Codepoints int `json:"codepoints"`
+	ASCII      int `json:"ascii"`
+	BMP        int `json:"bmp"`
+	Astral     int `json:"astral"`
+	Combining  int `json:"combining"`
+	LineEnds   int `json:"lineEnds"`
+	// the defects in the order of their offsets
+	Defects []Defect `json:"defects"`
+}
+
+type generator struct {
+	options  Options
+	encoding string
+	random   *rand.Rand
+	// output of the utf encodings, which defects are written into
+	output []byte
+	// code points of the other encodings, which are encoded at the end
+	codepoints []uint32
+	manifest   Manifest
+}
+
+// returns the encoding of the byte order mark of the other byte order, empty for utf-8 and the escaped encodings
+func otherByteOrder(encoding string) string {
+	switch encoding {
+	case types.UTF_16, types.UTF_16BE:
+		return types.UTF_16LE
+	case types.UTF_16LE:
+		return types.UTF_16BE
+	case types.UTF_32, types.UTF_32BE:
+		return types.UTF_32LE
+	case types.UTF_32LE:
+		return types.UTF_32BE
+	}
+	return ""
+}
+
+// returns an error when options ask for something encoding can't hold
+func check(encoding string, options Options) error {
+	if options.Length < 0 || options.LineLength < 0 {
+		return errors.New("negative length")
+	}
+	if options.ASCII < 0 || options.BMP < 0 || options.Astral < 0 || options.ASCII+options.BMP+options.Astral == 0 {
+		return errors.New("the weights of ascii, bmp and astral code points must be positive or 0, and not all 0")
+	}
+	for _, rate := range []float64{options.Combining, options.Overlong, options.LoneSurrogates, options.BeyondMax} {
+		if rate < 0 || rate > 1 {
+			return errors.New("rates must be between 0 and 1")
+		}
+	}
+	if options.Overlong+options.LoneSurrogates+options.BeyondMax > 1 {
+		return errors.New("the rates of the defects add up to more than 1")
+	}
+
+	hasDefects := options.Overlong != 0 || options.LoneSurrogates != 0 || options.BeyondMax != 0 || options.TruncatedTail || options.WrongEndianBOM
+	switch {
+	case !codec.IsUTF(encoding) && hasDefects:
+		return errors.New("defects are only written in utf-8, utf-16 and utf-32, " + strings.ToUpper(encoding) + " is escaped text")
+	case options.Overlong != 0 && encoding != types.UTF_8:
+		return errors.New("overlong sequences only exist in utf-8")
+	case options.BeyondMax != 0 && codec.UnitSize(encoding) == 2:
+		return errors.New("utf-16 can't hold values beyond U+10FFFF")
+	case options.WrongEndianBOM && encoding == types.UTF_8:
+		return errors.New("utf-8 has no byte order")
+	}
+	return nil
+}
+
+// appends a code unit of utf-16 or utf-32 as it is, in the byte order of the encoding
+func (g *generator) appendUnit(output []byte, unit uint32) []byte {
+	isBigEndian := utils.IsBigEndian(g.encoding)
+	if codec.UnitSize(g.encoding) == 2 {
+		if isBigEndian {
+			return append(output, byte(unit>>8), byte(unit))
+		}
+		return append(output, byte(unit), byte(unit>>8))
+	}
+	if isBigEndian {
+		return append(output, byte(unit>>24), byte(unit>>16), byte(unit>>8), byte(unit))
+	}
+	return append(output, byte(unit), byte(unit>>8), byte(unit>>16), byte(unit>>24))
+}
+
+// appends the encoded bits, which are a valid code point
+func (g *generator) appendCodepoint(output []byte, bits uint32) []byte {
+	switch codec.UnitSize(g.encoding) {
+	case 2:
+		return utils.AppendUTF16(output, bits, utils.IsBigEndian(g.encoding), types.REPLACE)
+	case 4:
+		return utils.AppendUTF32(output, bits, utils.IsBigEndian(g.encoding), types.REPLACE)
+	}
+	return utils.AppendUTF8(output, bits, types.REPLACE)
+}
+
+func (g *generator) add(bits uint32) {
+	if codec.IsUTF(g.encoding) {
+		g.output = g.appendCodepoint(g.output, bits)
+	} else {
+		g.codepoints = append(g.codepoints, bits)
+	}
+	g.manifest.Codepoints += 1
+}
+
+func (g *generator) addDefect(kind string, sequence []byte) {
+	g.manifest.Defects = append(g.manifest.Defects, Defect{Kind: kind, Offset: len(g.output), Bytes: hex.EncodeToString(sequence)})
+	g.output = append(g.output, sequence...)
+}
+
+// returns a random code point between first and last
+func (g *generator) between(first, last uint32) uint32 {
+	return first + g.random.Uint32N(last-first+1)
+}
+
+// returns a random printable ascii code point
+func (g *generator) ascii() uint32 {
+	return g.between(0x20, 0x7E)
+}
+
+// returns a random code point of the basic multilingual plane beyond ascii. surrogates aren't code points, and U+FEFF
+// and U+FFFE would read as byte order marks at the start
+func (g *generator) bmp() uint32 {
+	for {
+		bits := g.between(0x80, 0xFFFF)
+		if (bits < 0xD800 || bits > 0xDFFF) && bits != 0xFEFF && bits != 0xFFFE {
+			return bits
+		}
+	}
+}
+
+// returns a random code point beyond the basic multilingual plane
+func (g *generator) astral() uint32 {
+	return g.between(0x10000, 0x10FFFF)
+}
+
+// adds a character of a plane drawn by the weights of options
+func (g *generator) addCharacter() {
+	options := g.options
+	draw := g.random.Float64() * (options.ASCII + options.BMP + options.Astral)
+	switch {
+	case draw < options.ASCII:
+		g.add(g.ascii())
+		g.manifest.ASCII += 1
+	case draw < options.ASCII+options.BMP:
+		g.add(g.bmp())
+		g.manifest.BMP += 1
+	default:
+		g.add(g.astral())
+		g.manifest.Astral += 1
+	}
+}
+
+// adds a defect of a kind drawn by the rates of options, or none
+func (g *generator) addDefects() {
+	options := g.options
+	draw := g.random.Float64()
+	switch {
+	case draw < options.Overlong:
+		bits := g.ascii()
+		// the leading bits of the longer forms are all 0, which makes them overlong
+		switch g.random.IntN(3) {
+		case 0:
+			g.addDefect(OVERLONG, []byte{0xC0 | byte(bits>>6), 0x80 | byte(bits&0x3F)})
+		case 1:
+			g.addDefect(OVERLONG, []byte{0xE0, 0x80 | byte(bits>>6), 0x80 | byte(bits&0x3F)})
+		default:
+			g.addDefect(OVERLONG, []byte{0xF0, 0x80, 0x80 | byte(bits>>6), 0x80 | byte(bits&0x3F)})
+		}
+	case draw < options.Overlong+options.LoneSurrogates:
+		bits := g.between(0xD800, 0xDFFF)
+		if g.encoding == types.UTF_8 {
+			g.addDefect(LONE_SURROGATE, []byte{0xED, 0x80 | byte(bits>>6&0x3F), 0x80 | byte(bits&0x3F)})
+		} else {
+			g.addDefect(LONE_SURROGATE, g.appendUnit(nil, bits))
+		}
+	case draw < options.Overlong+options.LoneSurrogates+options.BeyondMax:
+		if g.encoding == types.UTF_8 {
+			// the 4 byte form of U+110000-U+13FFFF, the bytes utf-8 would use if it went on
+			bits := g.between(0x110000, 0x13FFFF)
+			g.addDefect(BEYOND_MAX, []byte{0xF4, 0x80 | byte(bits>>12&0x3F), 0x80 | byte(bits>>6&0x3F), 0x80 | byte(bits&0x3F)})
+		} else {
+			// below 0x80000000, so it never reads as a byte order mark of the other byte order
+			g.addDefect(BEYOND_MAX, g.appendUnit(nil, g.between(0x110000, 0x7FFFFFFF)))
+		}
+	}
+}
+
+// adds the first bytes of a code point which takes more than one
+func (g *generator) addTruncatedTail() {
+	bits := g.astral()
+	if g.encoding == types.UTF_8 && g.random.IntN(2) == 0 {
+		bits = g.between(0x80, 0x7FF)
+	}
+	sequence := g.appendCodepoint(nil, bits)
+	g.addDefect(TRUNCATED_TAIL, sequence[:1+g.random.IntN(len(sequence)-1)])
+}
+
+// returns a corpus of random text in encoding and its manifest. the same seed and options generate the same corpus.
+// a defect is only ever written between two whole code points, so every defect decodes as malformed on its own
+func Generate(encoding string, options Options, seed uint64) ([]byte, Manifest, error) {
+	if err := check(encoding, options); err != nil {
+		return nil, Manifest{}, err
+	}
+
+	g := &generator{
+		options:  options,
+		encoding: encoding,
+		random:   rand.New(rand.NewPCG(seed, seed)),
+		manifest: Manifest{Encoding: encoding, Seed: seed, Defects: []Defect{}},
+	}
+
+	if options.WrongEndianBOM {
+		g.addDefect(WRONG_ENDIAN_BOM, utils.AppendBOM(nil, otherByteOrder(encoding)))
+	} else if options.AddBOM && codec.IsUTF(encoding) {
+		g.output = utils.AppendBOM(g.output, encoding)
+	}
+
+	column := 0
+	for g.manifest.Codepoints < options.Length {
+		g.addDefects()
+
+		if options.LineLength != 0 && column >= options.LineLength {
+			g.add('\n')
+			g.manifest.LineEnds += 1
+			column = 0
+			continue
+		}
+
+		g.addCharacter()
+		column += 1
+		if g.random.Float64() >= options.Combining {
+			continue
+		}
+		// combining diacritical marks, so that they read with any character
+		for count := 1 + g.random.IntN(3); count > 0 && g.manifest.Codepoints < options.Length; count -= 1 {
+			g.add(g.between(0x0300, 0x036F))
+			g.manifest.Combining += 1
+			column += 1
+		}
+	}
+
+	if options.TruncatedTail {
+		g.addTruncatedTail()
+	}
+
+	if !codec.IsUTF(encoding) {
+		output, err := codec.Encode(g.codepoints, encoding, codec.Options{ErrorMode: types.REPLACE})
+		if err != nil {
+			return nil, Manifest{}, err
+		}
+		g.output = output
+	}
+
+	g.manifest.Size = len(g.output)
+	return g.output, g.manifest, nil
+}
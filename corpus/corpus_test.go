@@ -0,0 +1,139 @@
+package corpus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"utfcoder/codec"
+	"utfcoder/types"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, test := range generateTestInputs {
+		output, manifest, err := Generate(test.encoding, test.options, 7)
+		if err != nil {
+			t.Errorf(`Generate(%v, %+v) = err=%v, Expected = err=<nil>`, test.encoding, test.options, err)
+			continue
+		}
+
+		codepoints, report, err := codec.Decode(output, test.encoding, codec.Options{ErrorMode: types.REPLACE})
+		if err != nil || len(report.Malformed) != 0 || len(codepoints) != test.options.Length {
+			t.Errorf(`Generate(%v, %+v) = decoded=%v, malformed=%v, err=%v, Expected = decoded=%v, malformed=[], err=<nil>`, test.encoding, test.options, len(codepoints), report.Malformed, err, test.options.Length)
+		}
+		if manifest.Codepoints != test.options.Length || manifest.Size != len(output) || manifest.ASCII+manifest.BMP+manifest.Astral+manifest.Combining+manifest.LineEnds != manifest.Codepoints {
+			t.Errorf(`Generate(%v, %+v) = manifest=%+v, Expected = manifest of %v code points in %v bytes`, test.encoding, test.options, manifest, test.options.Length, len(output))
+		}
+		if test.options.Astral == 0 && manifest.Astral != 0 {
+			t.Errorf(`Generate(%v, %+v) = astral=%v, Expected = astral=0`, test.encoding, test.options, manifest.Astral)
+		}
+	}
+}
+
+func TestGenerateDefects(t *testing.T) {
+	for _, test := range generateDefectsTestInputs {
+		output, manifest, err := Generate(test.encoding, test.options, 7)
+		if err != nil {
+			t.Errorf(`Generate(%v, %+v) = err=%v, Expected = err=<nil>`, test.encoding, test.options, err)
+			continue
+		}
+
+		kinds := map[string]int{}
+		for _, defect := range manifest.Defects {
+			sequence, _ := hex.DecodeString(defect.Bytes)
+			if !bytes.Equal(output[defect.Offset:defect.Offset+len(sequence)], sequence) {
+				t.Errorf(`Generate(%v, %+v) = bytes %x at %v, Expected = %v`, test.encoding, test.options, output[defect.Offset:defect.Offset+len(sequence)], defect.Offset, defect.Bytes)
+			}
+			kinds[defect.Kind] += 1
+		}
+		for _, kind := range test.kinds {
+			if kinds[kind] == 0 {
+				t.Errorf(`Generate(%v, %+v) = defects=%v, Expected = defects of kind %v`, test.encoding, test.options, kinds, kind)
+			}
+		}
+
+		// every defect decodes as malformed at its offset, and nothing else does
+		_, report, _ := codec.Decode(output, test.encoding, codec.Options{ErrorMode: types.SURROGATE_ESCAPE})
+		malformedAt := map[int]bool{}
+		for _, malformed := range report.Malformed {
+			malformedAt[malformed.Offset] = true
+			isDefect := false
+			for _, defect := range manifest.Defects {
+				isDefect = isDefect || (malformed.Offset >= defect.Offset && malformed.Offset < defect.Offset+len(defect.Bytes)/2)
+			}
+			if !isDefect {
+				t.Errorf(`Generate(%v, %+v) = malformed %+v, Expected = no malformed sequence outside the defects`, test.encoding, test.options, malformed)
+			}
+		}
+		for _, defect := range manifest.Defects {
+			if defect.Kind != WRONG_ENDIAN_BOM && !malformedAt[defect.Offset] {
+				t.Errorf(`Generate(%v, %+v) = defect %+v not malformed, Expected = malformed`, test.encoding, test.options, defect)
+			}
+		}
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	options := Options{Length: 200, ASCII: 1, BMP: 1, Astral: 1, Combining: 0.1, LoneSurrogates: 0.05}
+	first, _, _ := Generate(types.UTF_16LE, options, 1)
+	again, _, _ := Generate(types.UTF_16LE, options, 1)
+	other, _, _ := Generate(types.UTF_16LE, options, 2)
+
+	if !bytes.Equal(first, again) {
+		t.Errorf(`Generate(seed=1) = %x, Expected = %x`, again, first)
+	}
+	if bytes.Equal(first, other) {
+		t.Errorf(`Generate(seed=2) = %x, Expected = another corpus than seed 1`, other)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, test := range generateErrorsTestInputs {
+		if _, _, err := Generate(test.encoding, test.options, 7); err == nil {
+			t.Errorf(`Generate(%v, %+v) = err=<nil>, Expected = an error`, test.encoding, test.options)
+		}
+	}
+}
+
+var generateTestInputs = []struct {
+	encoding string
+	options  Options
+}{
+	{types.UTF_8, Options{Length: 500, ASCII: 70, BMP: 25, Astral: 5, Combining: 0.1, LineLength: 40}},
+	{types.UTF_16LE, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 1}},
+	{types.UTF_16BE, Options{Length: 500, ASCII: 0, BMP: 0, Astral: 1, AddBOM: true}},
+	{types.UTF_32LE, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 0, Combining: 0.5, LineLength: 10}},
+	{types.UTF_32BE, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 1}},
+	{types.ESCAPED_JSON, Options{Length: 500, BMP: 1, Astral: 1, Combining: 0.2}},
+	{types.UTF_8, Options{Length: 0, ASCII: 1}},
+}
+
+var generateDefectsTestInputs = []struct {
+	encoding string
+	options  Options
+	kinds    []string
+}{
+	{types.UTF_8, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 1, Overlong: 0.05, LoneSurrogates: 0.05, BeyondMax: 0.05, TruncatedTail: true},
+		[]string{OVERLONG, LONE_SURROGATE, BEYOND_MAX, TRUNCATED_TAIL}},
+	{types.UTF_16LE, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 1, LoneSurrogates: 0.1, TruncatedTail: true, WrongEndianBOM: true},
+		[]string{LONE_SURROGATE, TRUNCATED_TAIL, WRONG_ENDIAN_BOM}},
+	{types.UTF_16BE, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 1, LoneSurrogates: 0.1, TruncatedTail: true},
+		[]string{LONE_SURROGATE, TRUNCATED_TAIL}},
+	{types.UTF_32LE, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 1, LoneSurrogates: 0.05, BeyondMax: 0.05, TruncatedTail: true},
+		[]string{LONE_SURROGATE, BEYOND_MAX, TRUNCATED_TAIL}},
+	{types.UTF_32BE, Options{Length: 500, ASCII: 1, BMP: 1, Astral: 1, BeyondMax: 0.1, WrongEndianBOM: true},
+		[]string{BEYOND_MAX, WRONG_ENDIAN_BOM}},
+}
+
+var generateErrorsTestInputs = []struct {
+	encoding string
+	options  Options
+}{
+	{types.UTF_16LE, Options{Length: 10, ASCII: 1, Overlong: 0.1}},
+	{types.UTF_16BE, Options{Length: 10, ASCII: 1, BeyondMax: 0.1}},
+	{types.UTF_8, Options{Length: 10, ASCII: 1, WrongEndianBOM: true}},
+	{types.ESCAPED_JSON, Options{Length: 10, ASCII: 1, LoneSurrogates: 0.1}},
+	{types.UTF_8, Options{Length: 10}},
+	{types.UTF_8, Options{Length: 10, ASCII: 1, Overlong: 0.6, LoneSurrogates: 0.6}},
+	{types.UTF_8, Options{Length: -1, ASCII: 1}},
+	{"utf-7", Options{Length: 10, ASCII: 1}},
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"utfcoder/codec"
+)
+
+func runDetect(c *cli, args []string) int {
+	fs := c.newFlagSet("detect")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		return c.fail(exitUsage, "no file mentioned. use 'utfcoder detect file...', - reads stdin")
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		data, err := c.readSource(path)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			code = exitFailure
+			continue
+		}
+
+		detection := codec.Detect(data)
+		if len(detection.Encoding) == 0 {
+			fmt.Fprintf(c.stdout, "%v: unknown (%v)\n", path, detection.Reason)
+			code = max(code, exitInvalid)
+			continue
+		}
+
+		bom := ""
+		if detection.HasBOM {
+			bom = " with bom"
+		}
+		fmt.Fprintf(c.stdout, "%v: %v%v (%v)\n", path, detection.Encoding, bom, detection.Reason)
+	}
+
+	return code
+}
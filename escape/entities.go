@@ -0,0 +1,206 @@
+package escape
+
+import (
+	"bytes"
+	"fmt"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+//go:generate go run gen_entities.go -o entities_table.go entities.json
+
+// preferred named reference of every code point which has one, the shortest name wins
+var entityNames = map[uint32]string{}
+
+// html5 reads numeric references to the C1 controls as windows-1252 characters
+var numericReferenceReplacements = map[uint32]uint32{
+	0x80: 0x20AC, 0x82: 0x201A, 0x83: 0x0192, 0x84: 0x201E, 0x85: 0x2026, 0x86: 0x2020, 0x87: 0x2021, 0x88: 0x02C6,
+	0x89: 0x2030, 0x8A: 0x0160, 0x8B: 0x2039, 0x8C: 0x0152, 0x8E: 0x017D, 0x91: 0x2018, 0x92: 0x2019, 0x93: 0x201C,
+	0x94: 0x201D, 0x95: 0x2022, 0x96: 0x2013, 0x97: 0x2014, 0x98: 0x02DC, 0x99: 0x2122, 0x9A: 0x0161, 0x9B: 0x203A,
+	0x9C: 0x0153, 0x9E: 0x017E, 0x9F: 0x0178,
+}
+
+// the characters markup gives a meaning to, always written as the references xml predefines, which html5 has as well
+var markupReferences = map[uint32]string{'&': "amp;", '<': "lt;", '>': "gt;", '"': "quot;", '\'': "apos;"}
+
+// the longest name in the html5 table is "CounterClockwiseContourIntegral;"
+const maxEntityNameLength = 32
+
+func init() {
+	for name, codepoints := range entities {
+		if len(codepoints) != 1 || name[len(name)-1] != ';' {
+			continue
+		}
+
+		current, ok := entityNames[codepoints[0]]
+		if !ok || len(name) < len(current) || (len(name) == len(current) && name < current) {
+			entityNames[codepoints[0]] = name
+		}
+	}
+}
+
+func IsReferenceEncoding(encoding string) bool {
+	return encoding == types.HTML_ENTITIES || encoding == types.XML_CHARREF
+}
+
+// returns codepoints in charset, writing every code point charset can't hold as a character reference.
+// html-entities prefers named references, xml-charref only writes numeric ones. & < > " and ' are always written as
+// their predefined references, so the text stays text in elements and attributes
+func EncodeReferences(codepoints []uint32, targetEncoding string, charset string, errorMode types.ErrorMode) []byte {
+	var output = make([]byte, 0, len(codepoints))
+
+	limit := uint32(0x7F)
+	if charset == types.LATIN_1 {
+		limit = 0xFF
+	}
+
+	for _, bits := range codepoints {
+		if errorMode == types.SURROGATE_ESCAPE && utils.IsEscapedByte(bits) {
+			// the charsets are byte oriented, so the original byte is restored
+			output = append(output, byte(bits))
+			continue
+		}
+
+		if !utils.IsValidUnicodeRange(bits) {
+			bits = utils.GenerateUnknownCharacter(types.UTF_32)
+		}
+
+		if name, ok := markupReferences[bits]; ok {
+			output = fmt.Appendf(output, "&%v", name)
+		} else if bits <= limit {
+			output = append(output, byte(bits))
+		} else if name, ok := entityNames[bits]; ok && targetEncoding == types.HTML_ENTITIES {
+			output = fmt.Appendf(output, "&%v", name)
+		} else {
+			output = fmt.Appendf(output, "&#x%X;", bits)
+		}
+	}
+
+	return output
+}
+
+func isDigit(b byte, hex bool) bool {
+	_, ok := hexValue(b)
+	return (b >= '0' && b <= '9') || (hex && ok)
+}
+
+func isAlphanumeric(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// decodes &#NNN; and &#xHHH; at input[i]. returns the number of bytes consumed, 0 means input[i] is kept as literal text
+func (d *decoder) decodeNumericReference(i int) int {
+	input := d.input
+	html := d.flavor == types.HTML_ENTITIES
+
+	start := i + 2
+	hex := start < len(input) && (input[start] == 'x' || input[start] == 'X')
+	if hex {
+		start += 1
+	}
+
+	end := start
+	for end < len(input) && isDigit(input[end], hex) {
+		end += 1
+	}
+
+	hasSemicolon := end < len(input) && input[end] == ';'
+	if end == start || (!hasSemicolon && !html) {
+		d.report(i, min(end+1, len(input)), "malformed numeric character reference")
+		return 0
+	}
+
+	var bits uint32
+	for j := start; j < end && bits <= 0x10FFFF; j += 1 {
+		value, _ := hexValue(input[j])
+		if hex {
+			bits = bits<<4 | value
+		} else {
+			bits = bits*10 + value
+		}
+	}
+
+	size := end - i
+	if hasSemicolon {
+		size += 1
+	} else {
+		// html tolerates the missing semicolon, the reference is still worth reporting
+		d.report(i, end, "numeric character reference without ';'")
+	}
+
+	if replacement, ok := numericReferenceReplacements[bits]; ok && html {
+		bits = replacement
+	}
+
+	if bits != 0 && d.emit(bits) {
+		return size
+	}
+
+	if !html {
+		d.report(i, i+size, "character reference is not a unicode scalar value")
+		return 0
+	}
+
+	// html replaces NUL, surrogates and anything beyond U+10FFFF with U+FFFD
+	d.emit(utils.GenerateUnknownCharacter(types.UTF_32))
+	return size
+}
+
+// decodes the longest html5 named reference at input[i]. returns the number of bytes consumed, 0 means input[i] is kept as literal text
+func (d *decoder) decodeNamedReference(i int) int {
+	input := d.input
+
+	end := i + 1
+	for end < len(input) && end-i <= maxEntityNameLength && isAlphanumeric(input[end]) {
+		end += 1
+	}
+	if end < len(input) && input[end] == ';' {
+		end += 1
+	}
+
+	// legacy names without ';' may be followed by more letters, like "&notit;" which is "¬it;"
+	for j := end; j > i+1; j -= 1 {
+		if codepoints, ok := entities[string(input[i+1:j])]; ok {
+			for _, bits := range codepoints {
+				d.emit(bits)
+			}
+			return j - i
+		}
+	}
+
+	if end > i+1 && input[end-1] == ';' {
+		d.report(i, end, "unknown named character reference")
+	}
+	return 0
+}
+
+// decodes the references xml predefines at input[i]. returns the number of bytes consumed, 0 means input[i] is kept
+// as literal text
+func (d *decoder) decodePredefinedReference(i int) int {
+	for bits, name := range markupReferences {
+		if bytes.HasPrefix(d.input[i+1:], []byte(name)) {
+			d.emit(bits)
+			return len(name) + 1
+		}
+	}
+	return 0
+}
+
+func (d *decoder) decodeReferences() {
+	for i := 0; i < len(d.input); {
+		size := 0
+		if d.input[i] == '&' && i+1 < len(d.input) && d.input[i+1] == '#' {
+			size = d.decodeNumericReference(i)
+		} else if d.input[i] == '&' && d.flavor == types.HTML_ENTITIES {
+			size = d.decodeNamedReference(i)
+		} else if d.input[i] == '&' {
+			size = d.decodePredefinedReference(i)
+		}
+
+		if size == 0 {
+			d.keep(i, d.input[i])
+			size = 1
+		}
+		i += size
+	}
+}
@@ -0,0 +1,96 @@
+package escape
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+func TestEncodeReferences(t *testing.T) {
+	for _, test := range referenceEncodeTestInputs {
+		output := EncodeReferences(referenceTestCodepoints, test.encoding, test.charset, types.REPLACE)
+
+		if string(output) != test.expected {
+			t.Errorf(`EncodeReferences(%v, %v, %v) = output=%q, Expected = output=%q`, referenceTestCodepoints, test.encoding, test.charset, output, test.expected)
+		}
+	}
+}
+
+func TestDecodeReferences(t *testing.T) {
+	for _, test := range referenceDecodeTestInputs {
+		codepoints, _, malformed := Decode([]byte(test.input), test.encoding, "", types.REPLACE)
+
+		if !equalCodepoints(codepoints, test.expected) || len(malformed) != test.malformed {
+			t.Errorf(`Decode(%v, %v) = codepoints=%v, malformed=%v, Expected = codepoints=%v, malformed=%v`, test.input, test.encoding, codepoints, len(malformed), test.expected, test.malformed)
+		}
+	}
+}
+
+func TestReferencesRoundTrip(t *testing.T) {
+	// markup and text which looks like a reference must come back as it was
+	literal := []uint32{'T', 'o', 'm', ' ', '&', ' ', '<', 'b', '>', '"', '\'', '&', 'e', 'a', 'c', 'u', 't', 'e', ';', 0xE9, '&', '#', '3', '3', ';'}
+	for _, encoding := range []string{types.HTML_ENTITIES, types.XML_CHARREF} {
+		output := EncodeReferences(literal, encoding, types.ASCII, types.REPLACE)
+		codepoints, _, malformed := Decode(output, encoding, "", types.REPLACE)
+
+		if !equalCodepoints(codepoints, literal) || len(malformed) != 0 {
+			t.Errorf(`Decode(%q, %v) = codepoints=%v, malformed=%v, Expected = codepoints=%v, malformed=%v`, output, encoding, codepoints, malformed, literal, nil)
+		}
+	}
+
+	expected := "Tom &amp; &lt;b&gt;&quot;&apos;&amp;eacute;&eacute;&amp;#33;"
+	if output := EncodeReferences(literal, types.HTML_ENTITIES, types.ASCII, types.REPLACE); string(output) != expected {
+		t.Errorf(`EncodeReferences(%v, html-entities, ascii) = output=%q, Expected = output=%q`, literal, output, expected)
+	}
+}
+
+func TestReferencesRoundTripLatin1(t *testing.T) {
+	// the text between the references is latin-1 both ways, é and ÿ stay bytes, € becomes a reference
+	literal := []uint32{'c', 'a', 'f', 0xE9, ' ', 0x20AC, ' ', 0xFF, '&', 0x80}
+	for _, encoding := range []string{types.HTML_ENTITIES, types.XML_CHARREF} {
+		output := EncodeReferences(literal, encoding, types.LATIN_1, types.REPLACE)
+		codepoints, _, malformed := Decode(output, encoding, types.LATIN_1, types.REPLACE)
+
+		if !equalCodepoints(codepoints, literal) || len(malformed) != 0 {
+			t.Errorf(`Decode(%q, %v, latin-1) = codepoints=%v, malformed=%v, Expected = codepoints=%v, malformed=%v`, output, encoding, codepoints, malformed, literal, nil)
+		}
+	}
+
+	// read as utf-8, the latin-1 byte is reported where it is
+	input := []byte("caf\xe9 &euro;")
+	codepoints, _, malformed := Decode(input, types.HTML_ENTITIES, types.ASCII, types.REPLACE)
+	expected := []uint32{'c', 'a', 'f', 0xFFFD, ' ', 0x20AC}
+	if !equalCodepoints(codepoints, expected) || len(malformed) != 1 || malformed[0].Offset != 3 || malformed[0].Column != 4 {
+		t.Errorf(`Decode(%q, html-entities, ascii) = codepoints=%v, malformed=%v, Expected = codepoints=%v, malformed at offset 3, column 4`, input, codepoints, malformed, expected)
+	}
+}
+
+// 'A', é (U+00E9), € (U+20AC), 😀 (U+1F600)
+var referenceTestCodepoints = []uint32{0x41, 0xE9, 0x20AC, 0x1F600}
+
+var referenceEncodeTestInputs = []struct {
+	encoding string
+	charset  string
+	expected string
+}{
+	{types.HTML_ENTITIES, types.ASCII, "A&eacute;&euro;&#x1F600;"},
+	{types.HTML_ENTITIES, types.LATIN_1, "A\xe9&euro;&#x1F600;"},
+	{types.XML_CHARREF, types.ASCII, "A&#xE9;&#x20AC;&#x1F600;"},
+	{types.XML_CHARREF, types.LATIN_1, "A\xe9&#x20AC;&#x1F600;"},
+}
+
+var referenceDecodeTestInputs = []struct {
+	encoding  string
+	input     string
+	expected  []uint32
+	malformed int
+}{
+	{types.HTML_ENTITIES, "caf&eacute; &amp; &#233;&#xE9;", []uint32{'c', 'a', 'f', 0xE9, ' ', '&', ' ', 0xE9, 0xE9}, 0},
+	{types.HTML_ENTITIES, "&#128;&#0;", []uint32{0x20AC, 0xFFFD}, 0},
+	{types.HTML_ENTITIES, "&notit;", []uint32{0xAC, 'i', 't', ';'}, 0},
+	{types.HTML_ENTITIES, "&NotNestedGreaterGreater;", []uint32{0x2AA2, 0x338}, 0},
+	{types.HTML_ENTITIES, "&copy 2024", []uint32{0xA9, ' ', '2', '0', '2', '4'}, 0},
+	{types.HTML_ENTITIES, "&bogus; & x", []uint32{'&', 'b', 'o', 'g', 'u', 's', ';', ' ', '&', ' ', 'x'}, 1},
+	{types.XML_CHARREF, "&#xE9;&eacute;", []uint32{0xE9, '&', 'e', 'a', 'c', 'u', 't', 'e', ';'}, 0},
+	{types.XML_CHARREF, "a &lt; b &amp;&amp c", []uint32{'a', ' ', '<', ' ', 'b', ' ', '&', '&', 'a', 'm', 'p', ' ', 'c'}, 0},
+	{types.XML_CHARREF, "&#xD800;&#65", []uint32{'&', '#', 'x', 'D', '8', '0', '0', ';', '&', '#', '6', '5'}, 2},
+}
@@ -0,0 +1,136 @@
+package escape
+
+import (
+	"fmt"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+var Flavors = [7]string{types.ESCAPED_JAVA, types.ESCAPED_JSON, types.ESCAPED_C, types.ESCAPED_GO, types.ESCAPED_PYTHON, types.ESCAPED_RUST, types.ESCAPED_JS}
+
+// short escape sequences of the ascii control characters, per flavor
+var controlEscapes = map[string]map[uint32]string{
+	types.ESCAPED_JAVA:   {0x08: `\b`, 0x09: `\t`, 0x0A: `\n`, 0x0C: `\f`, 0x0D: `\r`},
+	types.ESCAPED_JSON:   {0x08: `\b`, 0x09: `\t`, 0x0A: `\n`, 0x0C: `\f`, 0x0D: `\r`},
+	types.ESCAPED_C:      {0x07: `\a`, 0x08: `\b`, 0x09: `\t`, 0x0A: `\n`, 0x0B: `\v`, 0x0C: `\f`, 0x0D: `\r`},
+	types.ESCAPED_GO:     {0x07: `\a`, 0x08: `\b`, 0x09: `\t`, 0x0A: `\n`, 0x0B: `\v`, 0x0C: `\f`, 0x0D: `\r`},
+	types.ESCAPED_PYTHON: {0x09: `\t`, 0x0A: `\n`, 0x0D: `\r`},
+	types.ESCAPED_RUST:   {0x00: `\0`, 0x09: `\t`, 0x0A: `\n`, 0x0D: `\r`},
+	types.ESCAPED_JS:     {0x08: `\b`, 0x09: `\t`, 0x0A: `\n`, 0x0B: `\v`, 0x0C: `\f`, 0x0D: `\r`},
+}
+
+func IsFlavor(encoding string) bool {
+	for _, flavor := range Flavors {
+		if flavor == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+func isControl(bits uint32) bool {
+	return bits < 0x20 || bits == 0x7F
+}
+
+// writes an ascii control character the way flavor expects it in a string literal
+func appendControl(output []byte, flavor string, bits uint32) []byte {
+	if short, ok := controlEscapes[flavor][bits]; ok {
+		return append(output, short...)
+	}
+
+	switch flavor {
+	case types.ESCAPED_C:
+		// \x in C consumes every following hex digit, octal is limited to 3 digits
+		return fmt.Appendf(output, `\%03o`, bits)
+	case types.ESCAPED_GO, types.ESCAPED_PYTHON, types.ESCAPED_JS:
+		return fmt.Appendf(output, `\x%02x`, bits)
+	case types.ESCAPED_RUST:
+		return fmt.Appendf(output, `\u{%x}`, bits)
+	}
+	return fmt.Appendf(output, `\u%04x`, bits)
+}
+
+// writes a code point beyond ascii the way flavor expects it in a string literal
+func appendCodepoint(output []byte, flavor string, bits uint32) []byte {
+	switch flavor {
+	case types.ESCAPED_JAVA, types.ESCAPED_JSON:
+		// utf-16 based flavors write astral code points as a surrogate pair
+		if bits >= 0x10000 {
+			highSurrogate, lowSurrogate := utils.SplitSurrogates(bits)
+			return fmt.Appendf(output, `\u%04x\u%04x`, highSurrogate, lowSurrogate)
+		}
+		return fmt.Appendf(output, `\u%04x`, bits)
+	case types.ESCAPED_JS:
+		if bits >= 0x10000 {
+			return fmt.Appendf(output, `\u{%x}`, bits)
+		}
+		return fmt.Appendf(output, `\u%04x`, bits)
+	case types.ESCAPED_RUST:
+		return fmt.Appendf(output, `\u{%x}`, bits)
+	case types.ESCAPED_C:
+		// C forbids universal character names below U+00A0, the C1 controls are written as their utf-8 bytes
+		if bits < 0xA0 {
+			for _, b := range utils.AppendUTF8(nil, bits, types.REPLACE) {
+				output = fmt.Appendf(output, `\%03o`, b)
+			}
+			return output
+		}
+	case types.ESCAPED_PYTHON:
+		if bits < 0x100 {
+			return fmt.Appendf(output, `\x%02x`, bits)
+		}
+	}
+
+	if bits >= 0x10000 {
+		return fmt.Appendf(output, `\U%08x`, bits)
+	}
+	return fmt.Appendf(output, `\u%04x`, bits)
+}
+
+// writes a surrogate escape the way flavor can carry it, or false if flavor can't
+func appendEscapedByte(output []byte, flavor string, bits uint32) ([]byte, bool) {
+	switch flavor {
+	case types.ESCAPED_JAVA, types.ESCAPED_JSON, types.ESCAPED_JS, types.ESCAPED_PYTHON:
+		// lone surrogates are allowed in utf-16 based strings and python keeps them as is
+		return fmt.Appendf(output, `\u%04x`, bits), true
+	case types.ESCAPED_C:
+		// C and Go strings are byte sequences, so the original byte is restored. in octal, like the controls, since \x
+		// would take the hex digits after it too
+		return fmt.Appendf(output, `\%03o`, byte(bits)), true
+	case types.ESCAPED_GO:
+		return fmt.Appendf(output, `\x%02x`, byte(bits)), true
+	}
+	return output, false
+}
+
+// returns codepoints as ascii-safe source literal text of flavor
+func Encode(codepoints []uint32, flavor string, escapeScope types.EscapeScope, errorMode types.ErrorMode) []byte {
+	var output = make([]byte, 0, len(codepoints))
+
+	for _, bits := range codepoints {
+		if errorMode == types.SURROGATE_ESCAPE && utils.IsEscapedByte(bits) {
+			var ok bool
+			if output, ok = appendEscapedByte(output, flavor, bits); ok {
+				continue
+			}
+		}
+
+		if !utils.IsValidUnicodeRange(bits) {
+			bits = utils.GenerateUnknownCharacter(types.UTF_32)
+		}
+
+		if bits >= 0x80 {
+			output = appendCodepoint(output, flavor, bits)
+		} else if bits == '\\' || bits == '"' {
+			// written as is, they would end the literal or read as the start of an escape
+			output = append(output, '\\', byte(bits))
+		} else if isControl(bits) && (escapeScope == types.NON_PRINTABLE || (flavor == types.ESCAPED_JSON && bits < 0x20)) {
+			// json strings can't hold the C0 controls at all
+			output = appendControl(output, flavor, bits)
+		} else {
+			output = append(output, byte(bits))
+		}
+	}
+
+	return output
+}
@@ -0,0 +1,92 @@
+package escape
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+func TestEncode(t *testing.T) {
+	for _, test := range escapeTestInputs {
+		output := Encode(escapeTestCodepoints, test.flavor, types.NON_ASCII, types.REPLACE)
+
+		if string(output) != test.expected {
+			t.Errorf(`Encode(%v, %v) = output=%v, Expected = output=%v`, escapeTestCodepoints, test.flavor, string(output), test.expected)
+		}
+	}
+}
+
+func TestEncodeNonPrintable(t *testing.T) {
+	for _, test := range escapeNonPrintableTestInputs {
+		output := Encode([]uint32{'a', 0x09, 0x0A, 0x01, 0x7F}, test.flavor, types.NON_PRINTABLE, types.REPLACE)
+
+		if string(output) != test.expected {
+			t.Errorf(`Encode(%v) = output=%v, Expected = output=%v`, test.flavor, string(output), test.expected)
+		}
+	}
+}
+
+func TestEncodeSurrogateEscape(t *testing.T) {
+	codepoints := []uint32{'a', 0xDCE9}
+
+	if output := Encode(codepoints, types.ESCAPED_GO, types.NON_ASCII, types.SURROGATE_ESCAPE); string(output) != `a\xe9` {
+		t.Errorf(`Encode(%v) = output=%v, Expected = output=%v`, codepoints, string(output), `a\xe9`)
+	}
+	// C reads every hex digit after \x, a byte followed by "abc" would be one escape
+	if output := Encode(append(codepoints, 'a', 'b', 'c'), types.ESCAPED_C, types.NON_ASCII, types.SURROGATE_ESCAPE); string(output) != `a\351abc` {
+		t.Errorf(`Encode(%v) = output=%v, Expected = output=%v`, codepoints, string(output), `a\351abc`)
+	}
+	if decoded, _, _ := Decode([]byte(`a\351abc`), types.ESCAPED_C, "", types.SURROGATE_ESCAPE); !equalCodepoints(decoded, append(codepoints, 'a', 'b', 'c')) {
+		t.Errorf(`Decode(%v) = codepoints=%v, Expected = codepoints=%v`, `a\351abc`, decoded, append(codepoints, 'a', 'b', 'c'))
+	}
+	if output := Encode(codepoints, types.ESCAPED_RUST, types.NON_ASCII, types.SURROGATE_ESCAPE); string(output) != `a\u{fffd}` {
+		t.Errorf(`Encode(%v) = output=%v, Expected = output=%v`, codepoints, string(output), `a\u{fffd}`)
+	}
+}
+
+func TestEncodeLiteral(t *testing.T) {
+	codepoints := []uint32{'"', '\\', 'u', '0', '0', 'e', '9', '"', '\n'}
+
+	if output := Encode(codepoints, types.ESCAPED_JSON, types.NON_ASCII, types.REPLACE); string(output) != `\"\\u00e9\"\n` {
+		t.Errorf(`Encode(%v) = output=%v, Expected = output=%v`, codepoints, string(output), `\"\\u00e9\"\n`)
+	}
+	if output := Encode(codepoints, types.ESCAPED_PYTHON, types.NON_ASCII, types.REPLACE); string(output) != "\\\"\\\\u00e9\\\"\n" {
+		t.Errorf(`Encode(%v) = output=%q, Expected = output=%q`, codepoints, output, "\\\"\\\\u00e9\\\"\n")
+	}
+}
+
+// 'A', é (U+00E9), € (U+20AC), 😀 (U+1F600)
+var escapeTestCodepoints = []uint32{0x41, 0xE9, 0x20AC, 0x1F600}
+
+var escapeTestInputs = []struct {
+	flavor   string
+	expected string
+}{
+	{types.ESCAPED_JAVA, `A\u00e9\u20ac\ud83d\ude00`},
+	{types.ESCAPED_JSON, `A\u00e9\u20ac\ud83d\ude00`},
+	{types.ESCAPED_C, `A\u00e9\u20ac\U0001f600`},
+	{types.ESCAPED_GO, `A\u00e9\u20ac\U0001f600`},
+	{types.ESCAPED_PYTHON, `A\xe9\u20ac\U0001f600`},
+	{types.ESCAPED_RUST, `A\u{e9}\u{20ac}\u{1f600}`},
+	{types.ESCAPED_JS, `A\u00e9\u20ac\u{1f600}`},
+}
+
+func TestEncodeC1Controls(t *testing.T) {
+	codepoints := []uint32{0x80, 0x9F, 0xA0}
+
+	if output := Encode(codepoints, types.ESCAPED_C, types.NON_ASCII, types.REPLACE); string(output) != `\302\200\302\237\u00a0` {
+		t.Errorf(`Encode(%v) = output=%v, Expected = output=%v`, codepoints, string(output), `\302\200\302\237\u00a0`)
+	}
+	if output := Encode(codepoints, types.ESCAPED_GO, types.NON_ASCII, types.REPLACE); string(output) != `\u0080\u009f\u00a0` {
+		t.Errorf(`Encode(%v) = output=%v, Expected = output=%v`, codepoints, string(output), `\u0080\u009f\u00a0`)
+	}
+}
+
+var escapeNonPrintableTestInputs = []struct {
+	flavor   string
+	expected string
+}{
+	{types.ESCAPED_JAVA, `a\t\n\u0001\u007f`},
+	{types.ESCAPED_C, `a\t\n\001\177`},
+	{types.ESCAPED_PYTHON, `a\t\n\x01\x7f`},
+	{types.ESCAPED_RUST, `a\t\n\u{1}\u{7f}`},
+}
@@ -0,0 +1,63 @@
+//go:build ignore
+
+// generates entities_table.go from the WHATWG entities.json (https://html.spec.whatwg.org/entities.json). the copy
+// next to it is the one the table was generated from, the list of names is frozen so it won't change
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+var output = flag.String("o", "entities_table.go", "generated file")
+
+func main() {
+	flag.Parse()
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var entities map[string]struct {
+		Codepoints []uint32 `json:"codepoints"`
+	}
+	if err := json.Unmarshal(data, &entities); err != nil {
+		log.Fatal(err)
+	}
+
+	names := make([]string, 0, len(entities))
+	for name := range entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_entities.go from the WHATWG entities.json. DO NOT EDIT.\n\n")
+	buf.WriteString("package escape\n\n")
+	buf.WriteString("// html5 named character references without the leading '&'. names without ';' are the legacy forms\n")
+	buf.WriteString("var entities = map[string][]uint32{\n")
+	for _, name := range names {
+		var codepoints []string
+		for _, bits := range entities[name].Codepoints {
+			codepoints = append(codepoints, fmt.Sprintf("0x%x", bits))
+		}
+		fmt.Fprintf(&buf, "\t%q: {%v},\n", strings.TrimPrefix(name, "&"), strings.Join(codepoints, ", "))
+	}
+	buf.WriteString("}\n")
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,312 @@
+package escape
+
+import (
+	"utfcoder/types"
+	UTF8 "utfcoder/utf8"
+	"utfcoder/utils"
+)
+
+type decoder struct {
+	input      []byte
+	flavor     string
+	errorMode  types.ErrorMode
+	codepoints []uint32
+	// charset of the literal text between character references, utf-8 unless it is latin-1
+	charset string
+	// literal text and byte escapes not yet decoded, they are utf-8 once joined, and the input offset of every byte
+	pending   []byte
+	offsets   []int
+	escaped   int
+	malformed []types.Malformed
+	// line and column of the byte at offset
+	offset, line, column int
+}
+
+// the control characters of the short escape sequences, per flavor and letter
+var controlUnescapes = map[string]map[byte]uint32{}
+
+func init() {
+	for flavor, escapes := range controlEscapes {
+		controlUnescapes[flavor] = map[byte]uint32{}
+		for bits, short := range escapes {
+			controlUnescapes[flavor][short[1]] = bits
+		}
+	}
+}
+
+func IsSourceEncoding(encoding string) bool {
+	return IsFlavor(encoding) || IsReferenceEncoding(encoding) || encoding == types.CODEPOINTS || encoding == types.PERCENT
+}
+
+func hexValue(b byte) (uint32, bool) {
+	switch {
+	case b >= '0' && b <= '9':
+		return uint32(b - '0'), true
+	case b >= 'a' && b <= 'f':
+		return uint32(b-'a') + 10, true
+	case b >= 'A' && b <= 'F':
+		return uint32(b-'A') + 10, true
+	}
+	return 0, false
+}
+
+// returns the value of the hex digits in input[from:to], false if any of them isn't a hex digit or input is too short
+func parseHex(input []byte, from int, to int) (uint32, bool) {
+	if from >= to || to > len(input) {
+		return 0, false
+	}
+
+	var bits uint32
+	for i := from; i < to; i += 1 {
+		value, ok := hexValue(input[i])
+		if !ok || bits > 0x10FFFF {
+			return 0, false
+		}
+		bits = bits<<4 | value
+	}
+	return bits, true
+}
+
+func isOctal(b byte) bool {
+	return b >= '0' && b <= '7'
+}
+
+// keeps b, which was read at offset of the input, to be decoded with the text around it
+func (d *decoder) keep(offset int, b byte) {
+	d.pending = append(d.pending, b)
+	d.offsets = append(d.offsets, offset)
+}
+
+// decodes the kept bytes, reporting the sequences which aren't text of the charset at the offset they were read at
+func (d *decoder) flush() {
+	if len(d.pending) == 0 {
+		return
+	}
+
+	if d.charset == types.LATIN_1 {
+		for _, b := range d.pending {
+			d.codepoints = append(d.codepoints, uint32(b))
+		}
+	} else {
+		codepoints, escaped, malformed := UTF8.Decode(d.pending, d.errorMode)
+		d.codepoints = append(d.codepoints, codepoints...)
+		d.escaped += escaped
+		for _, sequence := range malformed {
+			d.add(d.offsets[sequence.Offset], sequence.Bytes, sequence.Reason)
+		}
+	}
+	d.pending, d.offsets = d.pending[:0], d.offsets[:0]
+}
+
+// moves the tracked line and column forward to offset
+func (d *decoder) seek(offset int) {
+	for ; d.offset < offset; d.offset += 1 {
+		if d.input[d.offset] == '\n' {
+			d.line, d.column = d.line+1, 1
+		} else if d.input[d.offset]&0xc0 != 0x80 || d.charset == types.LATIN_1 {
+			d.column += 1
+		}
+	}
+}
+
+func (d *decoder) add(offset int, bytes []byte, reason string) {
+	d.seek(offset)
+	d.malformed = append(d.malformed, types.Malformed{Offset: offset, Line: d.line, Column: d.column, Bytes: bytes, Reason: reason})
+}
+
+// reports input[from:to], after the kept bytes before it
+func (d *decoder) report(from int, to int, reason string) {
+	d.flush()
+	d.add(from, d.input[from:to], reason)
+}
+
+// appends a decoded code point, false if it isn't a unicode scalar value
+func (d *decoder) emit(bits uint32) bool {
+	if d.errorMode == types.SURROGATE_ESCAPE && utils.IsEscapedByte(bits) {
+		d.escaped += 1
+	} else if !utils.IsValidUnicodeRange(bits) {
+		return false
+	}
+
+	d.flush()
+	d.codepoints = append(d.codepoints, bits)
+	return true
+}
+
+// decodes \uXXXX (joining surrogate pair escapes), \u{X...}, \UXXXXXXXX and \xXX at input[i].
+// returns the number of bytes consumed, 0 means input[i] is kept as literal text
+func (d *decoder) unescape(i int) int {
+	input := d.input
+	if i+1 >= len(input) {
+		return 0
+	}
+
+	var bits uint32
+	var size int
+	var ok bool
+
+	switch input[i+1] {
+	case 'u':
+		if i+2 < len(input) && input[i+2] == '{' {
+			end := i + 3
+			for end < len(input) && end < i+10 && input[end] != '}' {
+				end += 1
+			}
+			if end < len(input) && input[end] == '}' {
+				bits, ok = parseHex(input, i+3, end)
+			}
+			size = end - i + 1
+		} else {
+			bits, ok = parseHex(input, i+2, i+6)
+			size = 6
+
+			// a high surrogate escape directly followed by a low surrogate escape is one astral code point
+			if ok && bits >= 0xD800 && bits <= 0xDBFF && i+11 < len(input) && input[i+6] == '\\' && input[i+7] == 'u' {
+				if low, lowOk := parseHex(input, i+8, i+12); lowOk && low >= 0xDC00 && low <= 0xDFFF {
+					bits = (bits-0xD800)<<10 | (low - 0xDC00) + 0x10000
+					size = 12
+				}
+			}
+		}
+	case 'U':
+		bits, ok = parseHex(input, i+2, i+10)
+		size = 10
+	case 'x':
+		bits, ok = parseHex(input, i+2, i+4)
+		size = 4
+
+		// C and Go strings are byte sequences, \x is a byte of the utf-8 text
+		if ok && (d.flavor == types.ESCAPED_C || d.flavor == types.ESCAPED_GO) {
+			d.keep(i, byte(bits))
+			return size
+		}
+	default:
+		if bits, ok := controlUnescapes[d.flavor][input[i+1]]; ok {
+			d.keep(i, byte(bits))
+			return 2
+		}
+		if d.flavor == types.ESCAPED_C && isOctal(input[i+1]) {
+			// up to 3 octal digits, the way the encoder writes the other controls
+			end := i + 1
+			for end < len(input) && end < i+4 && isOctal(input[end]) {
+				bits = bits<<3 | uint32(input[end]-'0')
+				end += 1
+			}
+			d.keep(i, byte(bits))
+			return end - i
+		}
+		return 0
+	}
+
+	if size > len(input)-i {
+		size = len(input) - i
+	}
+
+	if !ok {
+		d.report(i, i+size, "malformed escape sequence")
+		return 0
+	}
+
+	if !d.emit(bits) {
+		if bits >= 0xD800 && bits <= 0xDFFF {
+			d.report(i, i+size, "lone surrogate escape")
+		} else {
+			d.report(i, i+size, "escape beyond U+10FFFF")
+		}
+		return 0
+	}
+
+	return size
+}
+
+func (d *decoder) decodeBackslashes() {
+	for i := 0; i < len(d.input); {
+		if d.input[i] == '\\' && i+1 < len(d.input) && (d.input[i+1] == '\\' || d.input[i+1] == '"') {
+			// an escaped backslash or quote, whatever follows it is literal text
+			d.keep(i+1, d.input[i+1])
+			i += 2
+		} else if d.input[i] == '\\' {
+			size := d.unescape(i)
+			if size == 0 {
+				// not an escape of a code point, it stays literal text
+				d.keep(i, '\\')
+				size = 1
+			}
+			i += size
+		} else {
+			d.keep(i, d.input[i])
+			i += 1
+		}
+	}
+}
+
+func (d *decoder) decodePercent() {
+	for i := 0; i < len(d.input); {
+		if d.input[i] != '%' {
+			d.keep(i, d.input[i])
+			i += 1
+		} else if bits, ok := parseHex(d.input, i+1, i+3); ok {
+			d.keep(i, byte(bits))
+			i += 3
+		} else {
+			d.report(i, min(i+3, len(d.input)), "malformed percent escape")
+			d.keep(i, d.input[i])
+			i += 1
+		}
+	}
+}
+
+func isSeparator(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == ',' || b == ';'
+}
+
+func (d *decoder) decodeCodepoints() {
+	for i := 0; i < len(d.input); {
+		if isSeparator(d.input[i]) {
+			i += 1
+			continue
+		}
+
+		end := i
+		for end < len(d.input) && !isSeparator(d.input[end]) {
+			end += 1
+		}
+
+		token := d.input[i:end]
+		isNotation := len(token) >= 6 && len(token) <= 8 && (token[0] == 'U' || token[0] == 'u') && token[1] == '+'
+
+		if bits, ok := parseHex(token, 2, len(token)); !isNotation || !ok {
+			d.report(i, end, "malformed code point, expected U+XXXX")
+			d.emit(utils.GenerateUnknownCharacter(types.UTF_32))
+		} else if !d.emit(bits) {
+			d.report(i, end, "code point is not a unicode scalar value")
+			d.emit(utils.GenerateUnknownCharacter(types.UTF_32))
+		}
+
+		i = end
+	}
+}
+
+// returns the code points of escaped text, the number of bytes carried as surrogate escapes and the malformed escapes
+// and bytes. malformed escapes and references are kept as literal text, malformed code points become U+FFFD. the text
+// between character references is read in charset, which is latin-1 or ascii read as utf-8. the other sources are utf-8
+func Decode(input []byte, sourceEncoding string, charset string, errorMode types.ErrorMode) ([]uint32, int, []types.Malformed) {
+	d := decoder{input: input, flavor: sourceEncoding, errorMode: errorMode, codepoints: make([]uint32, 0, len(input)), line: 1, column: 1}
+	if IsReferenceEncoding(sourceEncoding) {
+		d.charset = charset
+	}
+
+	switch sourceEncoding {
+	case types.CODEPOINTS:
+		d.decodeCodepoints()
+	case types.PERCENT:
+		d.decodePercent()
+	case types.HTML_ENTITIES, types.XML_CHARREF:
+		d.decodeReferences()
+	default:
+		d.decodeBackslashes()
+	}
+	d.flush()
+
+	return d.codepoints, d.escaped, d.malformed
+}
@@ -0,0 +1,105 @@
+package escape
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+func equalCodepoints(a []uint32, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDecode(t *testing.T) {
+	for _, test := range unescapeTestInputs {
+		codepoints, _, malformed := Decode([]byte(test.input), test.encoding, "", types.REPLACE)
+
+		if !equalCodepoints(codepoints, test.expected) || len(malformed) != 0 {
+			t.Errorf(`Decode(%v, %v) = codepoints=%v, malformed=%v, Expected = codepoints=%v, malformed=%v`, test.input, test.encoding, codepoints, malformed, test.expected, nil)
+		}
+	}
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	for _, flavor := range Flavors {
+		for _, scope := range []types.EscapeScope{types.NON_ASCII, types.NON_PRINTABLE} {
+			escaped := Encode(escapeTestCodepoints, flavor, scope, types.REPLACE)
+			codepoints, _, malformed := Decode(escaped, flavor, "", types.REPLACE)
+
+			if !equalCodepoints(codepoints, escapeTestCodepoints) || len(malformed) != 0 {
+				t.Errorf(`Decode(%v, %v) = codepoints=%v, malformed=%v, Expected = codepoints=%v, malformed=%v`, string(escaped), flavor, codepoints, malformed, escapeTestCodepoints, nil)
+			}
+		}
+	}
+}
+
+func TestDecodeRoundTripLiteral(t *testing.T) {
+	// text which looks like escapes, quotes and controls must come back as it was
+	literal := []uint32{'"', '\\', 'u', '0', '0', 'e', '9', '"', ' ', 'C', ':', '\\', 'n', 'e', 'w', '\\', '\\', '\t', '\n', 0x01, 0x7F, 0xE9, 0x80, 0x9F}
+	for _, flavor := range Flavors {
+		for _, scope := range []types.EscapeScope{types.NON_ASCII, types.NON_PRINTABLE} {
+			escaped := Encode(literal, flavor, scope, types.REPLACE)
+			codepoints, _, malformed := Decode(escaped, flavor, "", types.REPLACE)
+
+			if !equalCodepoints(codepoints, literal) || len(malformed) != 0 {
+				t.Errorf(`Decode(%v, %v) = codepoints=%v, malformed=%v, Expected = codepoints=%v, malformed=%v`, string(escaped), flavor, codepoints, malformed, literal, nil)
+			}
+		}
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	for _, test := range malformedUnescapeTestInputs {
+		codepoints, _, malformed := Decode([]byte(test.input), test.encoding, "", types.REPLACE)
+
+		if len(malformed) != 1 || malformed[0].Offset != test.offset || malformed[0].Line != test.line || malformed[0].Column != test.column {
+			t.Errorf(`Decode(%v, %v) = malformed=%v, Expected = offset=%v, line=%v, column=%v`, test.input, test.encoding, malformed, test.offset, test.line, test.column)
+		}
+		if !equalCodepoints(codepoints, test.expected) {
+			t.Errorf(`Decode(%v, %v) = codepoints=%v, Expected = codepoints=%v`, test.input, test.encoding, codepoints, test.expected)
+		}
+	}
+}
+
+var unescapeTestInputs = []struct {
+	encoding string
+	input    string
+	expected []uint32
+}{
+	{types.ESCAPED_JSON, `caf\u00e9`, []uint32{'c', 'a', 'f', 0xE9}},
+	{types.ESCAPED_JSON, `\ud83d\ude00!`, []uint32{0x1F600, '!'}},
+	{types.ESCAPED_JSON, `\\u00e9`, []uint32{'\\', 'u', '0', '0', 'e', '9'}},
+	{types.ESCAPED_JSON, `\"a\nb\"`, []uint32{'"', 'a', '\n', 'b', '"'}},
+	{types.ESCAPED_C, `\001\177\a`, []uint32{0x01, 0x7F, 0x07}},
+	{types.ESCAPED_RUST, `\0\\`, []uint32{0x00, '\\'}},
+	{types.ESCAPED_RUST, `\u{1F600}`, []uint32{0x1F600}},
+	{types.ESCAPED_PYTHON, `\xe9\U0001f600`, []uint32{0xE9, 0x1F600}},
+	{types.ESCAPED_GO, `\xc3\xa9`, []uint32{0xE9}},
+	{types.ESCAPED_JAVA, `é\u20ac`, []uint32{0xE9, 0x20AC}},
+	{types.CODEPOINTS, `U+0048 U+0069, u+1F600;U+10FFFF`, []uint32{'H', 'i', 0x1F600, 0x10FFFF}},
+	{types.PERCENT, `caf%C3%A9%20%f0%9f%98%80`, []uint32{'c', 'a', 'f', 0xE9, ' ', 0x1F600}},
+}
+
+var malformedUnescapeTestInputs = []struct {
+	encoding     string
+	input        string
+	offset       int
+	line, column int
+	expected     []uint32
+}{
+	{types.ESCAPED_JSON, "ok\n" + `é\u00g9`, 5, 2, 2, []uint32{'o', 'k', '\n', 0xE9, '\\', 'u', '0', '0', 'g', '9'}},
+	{types.ESCAPED_JSON, `\ud83dx`, 0, 1, 1, []uint32{'\\', 'u', 'd', '8', '3', 'd', 'x'}},
+	{types.ESCAPED_RUST, `\u{110000}`, 0, 1, 1, []uint32{'\\', 'u', '{', '1', '1', '0', '0', '0', '0', '}'}},
+	{types.CODEPOINTS, `U+0041 X+0042`, 7, 1, 8, []uint32{'A', 0xFFFD}},
+	{types.PERCENT, `100%`, 3, 1, 4, []uint32{'1', '0', '0', '%'}},
+	// bytes which aren't utf-8 are reported where they were read, escaped or not
+	{types.ESCAPED_JSON, "ok\nab\xff", 5, 2, 3, []uint32{'o', 'k', '\n', 'a', 'b', 0xFFFD}},
+	{types.PERCENT, `a%C3%28`, 1, 1, 2, []uint32{'a', 0xFFFD, '('}},
+}
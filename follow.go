@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+	"utfcoder/codec"
+	"utfcoder/transform"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+// how often -follow looks for appended bytes, truncation and rotation once it is at the end of the file
+var followInterval = 250 * time.Millisecond
+
+// follower converts a stream read in parts, writing the converted text a complete line at a time
+type follower struct {
+	cfg     config
+	options codec.Options
+	output  io.Writer
+	// the source encoding with its byte order, told from the first bytes of the stream
+	encoding string
+	// bytes of an incomplete code point, and code points of an incomplete line, waiting for the part after them
+	pending []byte
+	line    []uint32
+	// the first bytes of the stream have been decoded
+	isStarted  bool
+	hasWritten bool
+	// checks the output with -verify, against the code points of the incomplete line decoded without replacing bytes
+	verifier *codec.Verifier
+	expected []uint32
+}
+
+func newFollower(cfg config, output io.Writer) *follower {
+	f := &follower{cfg: cfg, options: cfg.options(), output: output}
+	if cfg.isVerify {
+		f.verifier = codec.NewVerifier(cfg.toEncoding, f.options)
+	}
+	return f
+}
+
+// decodes the next part of the stream and writes the lines it completes
+func (f *follower) feed(data []byte) error {
+	input := append(f.pending, data...)
+
+	if !f.isStarted {
+		// the first code units tell the byte order of the stream
+		if len(input) < codec.UnitSize(f.cfg.fromEncoding) {
+			f.pending = input
+			return nil
+		}
+		f.encoding = codec.StreamEncoding(input, f.cfg.fromEncoding)
+	}
+
+	complete := codec.Complete(input, f.encoding)
+	if err := f.decode(input[:complete], f.options.ErrorMode); err != nil {
+		return err
+	}
+	f.pending = slices.Clone(input[complete:])
+
+	if end := lastLineEnd(f.expected); end >= 0 {
+		f.expect(f.expected[:end+1])
+		f.expected = slices.Clone(f.expected[end+1:])
+	}
+	if end := lastLineEnd(f.line); end >= 0 {
+		err := f.write(f.line[:end+1])
+		f.line = slices.Clone(f.line[end+1:])
+		return err
+	}
+	return nil
+}
+
+// returns the index of the last code point ending a line of codepoints, -1 when there is none. a CR at the end waits
+// for the part after it, which may start with the LF of a CRLF
+func lastLineEnd(codepoints []uint32) int {
+	for idx := len(codepoints) - 1; idx >= 0; idx -= 1 {
+		if transform.IsLineEnding(codepoints[idx]) && (codepoints[idx] != '\r' || idx != len(codepoints)-1) {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (f *follower) decode(part []byte, errorMode types.ErrorMode) error {
+	if len(part) == 0 {
+		return nil
+	}
+
+	// the decoders skip a byte order mark at the start of their input, so a U+FEFF which starts a later part is kept
+	// behind a mark of its own
+	if f.isStarted {
+		part = append(utils.AppendBOM(nil, f.encoding), part...)
+	}
+	f.isStarted = true
+
+	options := f.options
+	options.ErrorMode = errorMode
+	codepoints, _, err := codec.Decode(part, f.encoding, options)
+	if err != nil {
+		return err
+	}
+	f.line = append(f.line, codepoints...)
+
+	if f.verifier != nil {
+		// the bytes replaced by U+FFFD have to come back as the bytes they are
+		if errorMode != types.SURROGATE_ESCAPE {
+			options.ErrorMode = types.SURROGATE_ESCAPE
+			if codepoints, _, err = codec.Decode(part, f.encoding, options); err != nil {
+				return err
+			}
+		}
+		f.expected = append(f.expected, codepoints...)
+	}
+	return nil
+}
+
+// adds the lines of expected to what the output has to decode back to
+func (f *follower) expect(expected []uint32) {
+	if f.verifier != nil && len(expected) != 0 {
+		f.verifier.Expect(codec.Apply(expected, f.options, &codec.Report{}))
+	}
+}
+
+func (f *follower) write(codepoints []uint32) error {
+	var report codec.Report
+	codepoints = codec.Apply(codepoints, f.options, &report)
+
+	options := f.options
+	options.AddBOM = f.cfg.addBOM && !f.hasWritten
+	output, err := codec.Encode(codepoints, f.cfg.toEncoding, options)
+	if err != nil {
+		return err
+	}
+
+	// the output is checked before it is written, a stream can't be taken back
+	if f.verifier != nil {
+		if err := f.verifier.Write(output); err != nil {
+			return err
+		}
+		if err := f.verifier.Err(); err != nil {
+			return err
+		}
+	}
+
+	f.hasWritten = true
+	_, err = f.output.Write(output)
+	return err
+}
+
+// writes what is left of the stream, a file which was truncated or replaced starts over after it
+func (f *follower) flush() error {
+	if !f.isStarted && len(f.pending) != 0 {
+		f.encoding = codec.StreamEncoding(f.pending, f.cfg.fromEncoding)
+	}
+	// the bytes of an incomplete code point are carried as surrogate escapes, which the encoders replace unless the
+	// errors are escaped anyway
+	err := f.decode(f.pending, types.SURROGATE_ESCAPE)
+	f.expect(f.expected)
+	if err == nil && len(f.line) != 0 {
+		err = f.write(f.line)
+	}
+
+	f.pending, f.line, f.expected, f.isStarted = nil, nil, nil, false
+	return err
+}
+
+// writes what is left of the stream, and checks that the output reached its end
+func (f *follower) close() error {
+	if err := f.flush(); err != nil {
+		return err
+	}
+	if f.verifier != nil {
+		return f.verifier.Close()
+	}
+	return nil
+}
+
+// converts the source as it grows, like tail -F: a truncated source is followed from its start, and a source which
+// was replaced (rotated) is read to its end before the new file at its path is followed. stdin is converted until
+// it ends
+func (c *cli) follow(ctx context.Context, cfg config) int {
+	output := c.stdout
+	if len(cfg.targetFile) != 0 {
+		target, err := os.OpenFile(cfg.targetFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, defaultFileMode)
+		if err != nil {
+			return c.fail(exitFailure, err)
+		}
+		defer target.Close()
+		output = target
+	}
+
+	f := newFollower(cfg, output)
+	buffer := make([]byte, 64*1024)
+
+	if cfg.sourceFile == "-" {
+		for {
+			n, err := c.stdin.Read(buffer)
+			if n > 0 {
+				if err := f.feed(buffer[:n]); err != nil {
+					return c.failConversion(err)
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return c.fail(exitFailure, err)
+			}
+		}
+		if err := f.close(); err != nil {
+			return c.failConversion(err)
+		}
+		return exitOK
+	}
+
+	source, err := os.Open(cfg.sourceFile)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+	defer func() { source.Close() }()
+	var offset int64
+
+	for {
+		n, err := source.Read(buffer)
+		if n > 0 {
+			offset += int64(n)
+			if err := f.feed(buffer[:n]); err != nil {
+				return c.failConversion(err)
+			}
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return c.fail(exitFailure, err)
+		}
+
+		// at the end of the file, which may have been truncated or replaced since
+		if info, err := os.Stat(cfg.sourceFile); err == nil {
+			current, _ := source.Stat()
+			if current != nil && !os.SameFile(current, info) {
+				if next, err := os.Open(cfg.sourceFile); err == nil {
+					fmt.Fprintf(c.stderr, "%v: file replaced, following the new file\n", cfg.sourceFile)
+					if err := f.flush(); err != nil {
+						return c.failConversion(err)
+					}
+					source.Close()
+					source, offset = next, 0
+					continue
+				}
+			} else if info.Size() < offset {
+				fmt.Fprintf(c.stderr, "%v: file truncated, following it from the start\n", cfg.sourceFile)
+				if err := f.flush(); err != nil {
+					return c.failConversion(err)
+				}
+				if _, err := source.Seek(0, io.SeekStart); err != nil {
+					return c.fail(exitFailure, err)
+				}
+				offset = 0
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := f.close(); err != nil {
+				return c.failConversion(err)
+			}
+			return exitOK
+		case <-time.After(followInterval):
+		}
+	}
+}
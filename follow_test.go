@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"utfcoder/types"
+)
+
+func TestFollowerFeed(t *testing.T) {
+	for _, test := range followerTestInputs {
+		var output bytes.Buffer
+		f := newFollower(config{fromEncoding: test.fromEncoding, toEncoding: types.UTF_8, errorMode: types.REPLACE}, &output)
+
+		var written []string
+		for _, part := range test.parts {
+			f.feed(part)
+			written = append(written, output.String())
+			output.Reset()
+		}
+		f.flush()
+		written = append(written, output.String())
+
+		if len(written) != len(test.written) {
+			t.Errorf(`feed(%v) = written=%q, Expected = written=%q`, test.parts, written, test.written)
+			continue
+		}
+		for idx := range written {
+			if written[idx] != test.written[idx] {
+				t.Errorf(`feed(%v) = written=%q, Expected = written=%q`, test.parts, written, test.written)
+				break
+			}
+		}
+	}
+}
+
+// written holds what every part completed, and last what flush wrote
+var followerTestInputs = []struct {
+	fromEncoding string
+	parts        [][]byte
+	written      []string
+}{
+	{types.UTF_8, [][]byte{[]byte("one\ntw"), []byte("o\n")}, []string{"one\n", "two\n", ""}},
+	{types.UTF_8, [][]byte{{'a', 0xE2, 0x80}, {0x99, '\n'}}, []string{"", "a’\n", ""}},
+	{types.UTF_8, [][]byte{[]byte("no line end")}, []string{"", "no line end"}},
+	// the surrogate pair is cut between the parts
+	{types.UTF_16LE, [][]byte{{'a', 0x00, '\n', 0x00, 0x3D, 0xD8}, {0x00, 0xDE, '\n'}, {0x00}}, []string{"a\n", "", "😀\n", ""}},
+	// the byte order is told by the byte order mark of the first part
+	{types.UTF_16, [][]byte{{0xFF}, {0xFE, 'h', 0x00}, {'\n', 0x00}}, []string{"", "", "h\n", ""}},
+	// a U+FEFF starting a later part is text, not a byte order mark
+	{types.UTF_16LE, [][]byte{{'a', 0x00}, {0xFF, 0xFE, '\n', 0x00}}, []string{"", "a\ufeff\n", ""}},
+	// without a byte order mark the byte order is told the way convert tells it
+	{types.UTF_32, [][]byte{{0x00, 0xF6, 0x01, 0x00, '\n', 0x00, 0x00, 0x00}}, []string{"😀\n", ""}},
+	{types.UTF_16, [][]byte{{0x00, 0xD8, 0x00, 0xDC, '\n', 0x00}}, []string{"\U00010000\n", ""}},
+	// every line end -eol knows completes a line, a CR at the end of a part only with the part after it
+	{types.UTF_8, [][]byte{[]byte("a\rb\u0085c\u2028d"), []byte("\u2029e\r"), []byte("\nf\r"), []byte("g")}, []string{"a\rb\u0085c\u2028", "d\u2029", "e\r\n", "f\r", "g"}},
+	{types.UTF_32LE, [][]byte{{'a', 0x00, 0x00}, {0x00, '\n', 0x00, 0x00, 0x00, 'b'}}, []string{"", "a\n", "\ufffd"}},
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"utfcoder/corpus"
+	"utfcoder/logger"
+)
+
+func runGen(c *cli, args []string) int {
+	var toEncoding, targetFile, manifestFile string
+	var seed uint64
+	var options corpus.Options
+
+	fs := c.newFlagSet("gen")
+	lowerStringVar(fs, &toEncoding, "to", "", "target `encoding`")
+	fs.StringVar(&targetFile, "t", "", "target `file` to write, stdout when empty")
+	fs.StringVar(&manifestFile, "manifest", "", "`file` to write the manifest to, the target file with .manifest.json appended by default, none for stdout")
+	fs.IntVar(&options.Length, "n", 1024, "number of `code points` to generate, line ends and combining marks included")
+	fs.Uint64Var(&seed, "seed", 0, "`seed` of the random text, the same seed and flags generate the same file. a random one when 0, the manifest records it")
+	fs.Float64Var(&options.ASCII, "ascii", 70, "relative `weight` of printable ascii characters")
+	fs.Float64Var(&options.BMP, "bmp", 25, "relative `weight` of the other characters of the basic multilingual plane")
+	fs.Float64Var(&options.Astral, "astral", 5, "relative `weight` of the characters beyond the basic multilingual plane, surrogate pairs in utf-16")
+	fs.Float64Var(&options.Combining, "combining", 0.05, "`rate` of characters followed by 1 to 3 combining marks")
+	fs.IntVar(&options.LineLength, "line-length", 80, "number of `code points` between line ends, no line ends when 0")
+	fs.BoolVar(&options.AddBOM, "bom", false, "starts the output with a byte order mark")
+	fs.Float64Var(&options.Overlong, "overlong", 0, "`rate` of ascii written as overlong utf-8 sequences, before every code point")
+	fs.Float64Var(&options.LoneSurrogates, "lone-surrogates", 0, "`rate` of lone surrogates, before every code point")
+	fs.Float64Var(&options.BeyondMax, "beyond-max", 0, "`rate` of values beyond U+10FFFF, before every code point. utf-16 can't hold them")
+	fs.BoolVar(&options.TruncatedTail, "truncated-tail", false, "ends the output with the first bytes of a code point")
+	fs.BoolVar(&options.WrongEndianBOM, "wrong-endian-bom", false, "starts the output with the byte order mark of the other byte order")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if !isValidTargetEncoding(toEncoding) {
+		return c.fail(exitUsage, "invalid target encoding provided. use '-to encoding', 'utfcoder list' lists them")
+	}
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	if len(manifestFile) == 0 && len(targetFile) != 0 {
+		manifestFile = targetFile + ".manifest.json"
+	}
+
+	output, manifest, err := corpus.Generate(toEncoding, options, seed)
+	if err != nil {
+		return c.fail(exitUsage, err)
+	}
+	logger.Log("Generated", manifest.Codepoints, "code points and", len(manifest.Defects), "defects with seed", seed)
+
+	if len(targetFile) == 0 {
+		if _, err := c.stdout.Write(output); err != nil {
+			return c.fail(exitFailure, err)
+		}
+	} else if err := writeFileAtomic(targetFile, output, ""); err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	if len(manifestFile) != 0 {
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return c.fail(exitFailure, err)
+		}
+		if err := writeFileAtomic(manifestFile, append(data, '\n'), ""); err != nil {
+			return c.fail(exitFailure, err)
+		}
+	}
+	return exitOK
+}
@@ -1,3 +1,8 @@
 module utfcoder
 
 go 1.24.1
+
+require (
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/text v0.28.0
+)
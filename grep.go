@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+	"utfcoder/codec"
+	"utfcoder/logger"
+	"utfcoder/transform"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+// returns a line of code points as the utf-8 text the pattern runs on, and for every byte of it (and the end) the
+// index of the code point of line it came from. normalized, every grapheme cluster is normalized to NFC on its own,
+// so a match still starts at a code point of line
+func searchText(line []uint32, isNormalized bool) (string, []int) {
+	var text []byte
+	var origins = make([]int, 0, len(line)+1)
+
+	add := func(bits uint32, origin int) {
+		r := rune(bits)
+		if utils.IsEscapedByte(bits) || !utf8.ValidRune(r) {
+			r = utf8.RuneError
+		}
+		text = utf8.AppendRune(text, r)
+		for len(origins) < len(text) {
+			origins = append(origins, origin)
+		}
+	}
+
+	if !isNormalized {
+		for idx, bits := range line {
+			add(bits, idx)
+		}
+	} else {
+		idx := 0
+		for _, cluster := range transform.GraphemeClusters(line) {
+			for _, bits := range transform.Normalize(cluster, types.NFC) {
+				add(bits, idx)
+			}
+			idx += len(cluster)
+		}
+	}
+
+	return string(text), append(origins, len(line))
+}
+
+// prints every match of pattern in the decoded file as path:line:column:offset:text, returns the number of matches
+func (c *cli) grepFile(path string, data []byte, fromEncoding string, pattern *regexp.Regexp, isNormalized bool) (int, error) {
+	// undecodable bytes keep their offsets, and never match anything but U+FFFD
+	codepoints, _, encoding, err := decodeSource(data, fromEncoding, codec.Options{ErrorMode: types.SURROGATE_ESCAPE})
+	if err != nil {
+		return 0, err
+	}
+	spans, err := codec.Spans(data, encoding, codepoints)
+	if err != nil {
+		return 0, err
+	}
+	logger.Log("Searching", path, "as", encoding)
+
+	count := 0
+	for start, lineNumber := 0, 1; start < len(codepoints); lineNumber += 1 {
+		end := start
+		for end < len(codepoints) && codepoints[end] != '\n' {
+			end += 1
+		}
+
+		line := codepoints[start:end]
+		if len(line) != 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+
+		text, origins := searchText(line, isNormalized)
+		for _, match := range pattern.FindAllStringIndex(text, -1) {
+			column := origins[match[0]]
+			offset := len(data)
+			if start+column < len(spans) {
+				offset = spans[start+column].Offset
+			}
+			fmt.Fprintf(c.stdout, "%v:%v:%v:%v:%v\n", path, lineNumber, column+1, offset, printableLine(line))
+			count += 1
+		}
+
+		start = end + 1
+	}
+	return count, nil
+}
+
+// returns line as utf-8, the undecodable bytes as U+FFFD
+func printableLine(line []uint32) string {
+	text, _ := searchText(line, false)
+	return text
+}
+
+func runGrep(c *cli, args []string) int {
+	var fromEncoding string
+	var isIgnoreCase, isIgnoreNormalization bool
+	var b batch
+
+	fs := c.newFlagSet("grep")
+	lowerStringVar(fs, &fromEncoding, "from", types.AUTO, "`encoding` of the files which don't name theirs as path:encoding, auto detects it for every file")
+	fs.BoolVar(&isIgnoreCase, "i", false, "matches upper and lower case alike")
+	fs.BoolVar(&isIgnoreNormalization, "ignore-normalization", false, "matches the text and the pattern normalized to NFC, so a precomposed é matches e and a combining accent")
+	fs.Var(&b.include, "include", "only searches files whose name or relative path matches `glob`, may be repeated")
+	fs.Var(&b.exclude, "exclude", "skips files and directories whose name or relative path matches `glob`, may be repeated")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() < 2 {
+		return c.fail(exitUsage, "no pattern and path mentioned. use 'utfcoder grep pattern path[:encoding]...', - reads stdin")
+	}
+	// matches are printed with their byte offsets, which escaped sources have none of
+	if !isLocatableEncoding(fromEncoding) {
+		return c.fail(exitUsage, "invalid source encoding provided. use '-from' with utf-8, utf-16 or utf-32, escaped text can't be searched")
+	}
+	for _, arg := range fs.Args()[1:] {
+		if _, encoding := catArgument(arg, fromEncoding); !isLocatableEncoding(encoding) {
+			return c.fail(exitUsage, fmt.Sprintf("invalid source encoding in %q. use path:encoding with utf-8, utf-16 or utf-32, escaped text can't be searched", arg))
+		}
+	}
+
+	expression := fs.Arg(0)
+	if isIgnoreNormalization {
+		var codepoints []uint32
+		for _, r := range expression {
+			codepoints = append(codepoints, uint32(r))
+		}
+		expression = printableLine(transform.Normalize(codepoints, types.NFC))
+	}
+	if isIgnoreCase {
+		expression = "(?i)" + expression
+	}
+	pattern, err := regexp.Compile(expression)
+	if err != nil {
+		return c.fail(exitUsage, "invalid pattern:", err)
+	}
+
+	code := exitInvalid
+	for _, arg := range fs.Args()[1:] {
+		root, encoding := catArgument(arg, fromEncoding)
+
+		jobs := []batchJob{{path: root}}
+		if root != "-" {
+			if jobs, err = b.collect([]string{root}); err != nil {
+				fmt.Fprintln(c.stderr, err)
+				code = exitFailure
+				continue
+			}
+		}
+
+		for _, job := range jobs {
+			data, err := c.readSource(job.path)
+			if err != nil {
+				fmt.Fprintln(c.stderr, err)
+				code = exitFailure
+				continue
+			}
+			if codec.IsBinary(data) {
+				logger.Log("Skipping binary file", job.path)
+				continue
+			}
+
+			count, err := c.grepFile(job.path, data, encoding, pattern, isIgnoreNormalization)
+			if err != nil {
+				fmt.Fprintln(c.stderr, job.path+":", err)
+				code = exitFailure
+				continue
+			}
+			if count > 0 && code == exitInvalid {
+				code = exitOK
+			}
+		}
+	}
+	return code
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"utfcoder/codec"
+	"utfcoder/types"
+	"utfcoder/ucd"
+	"utfcoder/utils"
+)
+
+// byteRange is the -range of inspect, from is inclusive and to exclusive
+type byteRange struct {
+	from, to int
+}
+
+// parses "from:to", either side may be left out and both take decimal or 0x hex offsets
+func parseByteRange(value string) (byteRange, error) {
+	r := byteRange{from: 0, to: -1}
+
+	from, to, ok := strings.Cut(value, ":")
+	if !ok {
+		return r, errors.New("invalid range " + value + ". use '-range from:to'")
+	}
+
+	var err error
+	if len(from) != 0 {
+		if r.from, err = parseOffset(from); err != nil {
+			return r, err
+		}
+	}
+	if len(to) != 0 {
+		if r.to, err = parseOffset(to); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+func parseOffset(value string) (int, error) {
+	offset, err := strconv.ParseInt(value, 0, 64)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid offset " + value)
+	}
+	return int(offset), nil
+}
+
+func (r byteRange) contains(span codec.Span) bool {
+	return span.Offset+span.Size > r.from && (r.to < 0 || span.Offset < r.to)
+}
+
+// returns the notes inspect highlights a code point with
+func inspectNote(bits uint32, sourceEncoding string, reasons map[int]string, span codec.Span) string {
+	if reason, ok := reasons[span.Offset]; ok {
+		return "invalid: " + reason
+	}
+	if utils.IsEscapedByte(bits) {
+		return "invalid"
+	}
+	if bits == 0xFEFF {
+		return "byte order mark in the text"
+	}
+	if span.Size == 4 && codec.UnitSize(sourceEncoding) == 2 {
+		high, low := utils.SplitSurrogates(bits)
+		return fmt.Sprintf("surrogate pair %04X %04X", high, low)
+	}
+	return ""
+}
+
+func printInspectLine(w io.Writer, offset int, source []byte, bits uint32, note string) {
+	if utils.IsEscapedByte(bits) {
+		fmt.Fprintf(w, "%08X  % -11X  %-8v  %-2v  %-11v  %-11v  %-11v  %v", offset, source, "-", "-", "-", "-", "-", "<undecodable byte>")
+	} else {
+		utf8Bytes := utils.AppendUTF8(nil, bits, types.REPLACE)
+		utf16Bytes := utils.AppendUTF16(nil, bits, true, types.REPLACE)
+		utf32Bytes := utils.AppendUTF32(nil, bits, true, types.REPLACE)
+		code := fmt.Sprintf("U+%04X", bits)
+		fmt.Fprintf(w, "%08X  % -11X  %-8v  %-2v  % -11X  % -11X  % -11X  %v", offset, source, code, ucd.Category(bits), utf8Bytes, utf16Bytes, utf32Bytes, ucd.Name(bits))
+	}
+
+	if len(note) != 0 {
+		fmt.Fprintf(w, "  [%v]", note)
+	}
+	fmt.Fprintln(w)
+}
+
+func runInspect(c *cli, args []string) int {
+	var sourceFile, fromEncoding, rangeFlag string
+	var at, context int
+
+	fs := c.newFlagSet("inspect")
+	sourceFlags(fs, &sourceFile, &fromEncoding)
+	fs.StringVar(&rangeFlag, "range", "", "only prints the code points in the byte `range` from:to, like 1024:2048 or 0x400:")
+	fs.IntVar(&at, "at", -1, "only prints the code point at byte `offset` and the code points around it")
+	fs.IntVar(&context, "context", 3, "number of code points printed before and after the one of -at")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	sourceFile, err := sourceArgument(fs, sourceFile)
+	if err != nil {
+		return c.fail(exitUsage, err)
+	}
+	// every code point is printed with the bytes it came from, which escaped sources can't tell
+	if !isLocatableEncoding(fromEncoding) {
+		return c.fail(exitUsage, "invalid source encoding provided. use '-from' with utf-8, utf-16 or utf-32, escaped text can't be inspected")
+	}
+
+	selected := byteRange{from: 0, to: -1}
+	if len(rangeFlag) != 0 {
+		if selected, err = parseByteRange(rangeFlag); err != nil {
+			return c.fail(exitUsage, err)
+		}
+	}
+
+	data, err := c.readSource(sourceFile)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	codepoints, report, encoding, err := decodeSource(data, fromEncoding, codec.Options{ErrorMode: types.SURROGATE_ESCAPE})
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	spans, err := codec.Spans(data, encoding, codepoints)
+	if err != nil {
+		return c.fail(exitUsage, err)
+	}
+
+	reasons := map[int]string{}
+	for _, malformed := range report.Malformed {
+		reasons[malformed.Offset] = malformed.Reason
+	}
+
+	first, last := 0, len(codepoints)
+	if at >= 0 {
+		// the code point whose bytes include the offset, and its context
+		idx := 0
+		for idx < len(spans)-1 && spans[idx].Offset+spans[idx].Size <= at {
+			idx += 1
+		}
+		first, last = max(idx-context, 0), min(idx+context+1, len(codepoints))
+	}
+
+	fmt.Fprintf(c.stdout, "%v: %v, %v bytes, %v code points\n", sourceFile, encoding, len(data), len(codepoints))
+	fmt.Fprintf(c.stdout, "%-8v  %-11v  %-8v  %-2v  %-11v  %-11v  %-11v  %v\n", "offset", "bytes", "code", "gc", "utf-8", "utf-16be", "utf-32be", "name")
+
+	if bomLength := codec.BOMLength(data, encoding); bomLength > 0 && first == 0 && selected.contains(codec.Span{Offset: 0, Size: bomLength}) {
+		printInspectLine(c.stdout, 0, data[:bomLength], 0xFEFF, "byte order mark")
+	}
+
+	for idx := first; idx < last; idx += 1 {
+		span := spans[idx]
+		if !selected.contains(span) {
+			continue
+		}
+
+		source := data[span.Offset : span.Offset+span.Size]
+		printInspectLine(c.stdout, span.Offset, source, codepoints[idx], inspectNote(codepoints[idx], encoding, reasons, span))
+	}
+
+	return exitOK
+}
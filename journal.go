@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// journalEntry is a line of the journal of batch, written before the converted file replaces anything
+type journalEntry struct {
+	// source file, absolute like every path of the journal
+	Path string `json:"path"`
+	// where the converted file is written, Path for in place conversion
+	Target string `json:"target"`
+	// encoding the source file was decoded from
+	Encoding string `json:"encoding"`
+	// sha-256 of the source file and of the converted file
+	Checksum  string `json:"checksum"`
+	Converted string `json:"converted"`
+	// copy of the source file, empty without -backup
+	Backup string `json:"backup,omitempty"`
+}
+
+// journal records what batch converts, one json line per file, so an interrupted run can be resumed and reverted
+type journal struct {
+	path string
+	file *os.File
+	// the last entry of every target, from the journal of an earlier run
+	entries map[string]journalEntry
+	mutex   sync.Mutex
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// returns the entries of the journal at path, oldest first, and the length of its complete lines. a run which died
+// while writing leaves a partial last line, which has no file written to it yet
+func readJournal(path string) ([]journalEntry, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	complete := bytes.LastIndexByte(data, '\n') + 1
+
+	var entries []journalEntry
+	for idx, line := range bytes.Split(data[:complete], []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, 0, fmt.Errorf("invalid journal %v, line %v: %w", path, idx+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, complete, nil
+}
+
+// opens the journal at path for appending, reading the entries of an earlier run when it exists. a dry run only
+// reads it
+func openJournal(path string, isDryRun bool) (*journal, error) {
+	j := &journal{entries: map[string]journalEntry{}}
+
+	var err error
+	if j.path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+
+	entries, complete, err := readJournal(j.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		j.entries[entry.Target] = entry
+	}
+
+	if isDryRun {
+		return j, nil
+	}
+
+	if j.file, err = os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600); err != nil {
+		return nil, err
+	}
+	// new entries go after the last complete line
+	if err := j.file.Truncate(int64(complete)); err != nil {
+		j.file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// returns whether target holds what an earlier run converted it to
+func (j *journal) isConverted(target string, targetData []byte) bool {
+	entry, ok := j.entries[target]
+	return ok && entry.Converted == checksum(targetData)
+}
+
+// appends entry to the journal and syncs it, before the file it is about is written
+func (j *journal) record(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("cannot write journal %v: %w", j.path, err)
+	}
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	if j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// what revert did with a file
+const (
+	revertRestored  = "restored"
+	revertRemoved   = "removed"
+	revertUnchanged = "unchanged"
+	revertFailed    = "failed"
+)
+
+// restores the file of entry from its backup, or removes a converted copy written next to an untouched source
+func revertEntry(entry journalEntry, isDryRun bool) (string, string) {
+	data, err := os.ReadFile(entry.Target)
+	if errors.Is(err, os.ErrNotExist) && entry.Target != entry.Path {
+		return revertUnchanged, "already removed"
+	}
+	if err != nil {
+		return revertFailed, err.Error()
+	}
+
+	switch checksum(data) {
+	case entry.Checksum:
+		return revertUnchanged, "already the original"
+	case entry.Converted:
+	default:
+		return revertFailed, "changed since it was converted, left as it is"
+	}
+
+	if entry.Target != entry.Path {
+		if isDryRun {
+			return revertRemoved, "would remove the converted copy"
+		}
+		if err := os.Remove(entry.Target); err != nil {
+			return revertFailed, err.Error()
+		}
+		return revertRemoved, "converted copy"
+	}
+
+	if len(entry.Backup) == 0 {
+		return revertFailed, "converted in place without a backup"
+	}
+	backup, err := os.ReadFile(entry.Backup)
+	if err != nil {
+		return revertFailed, err.Error()
+	}
+	if checksum(backup) != entry.Checksum {
+		return revertFailed, "backup " + entry.Backup + " differs from the original"
+	}
+
+	if isDryRun {
+		return revertRestored, "would restore " + entry.Encoding + " from " + entry.Backup
+	}
+	if err := writeFileAtomic(entry.Target, backup, entry.Backup); err != nil {
+		return revertFailed, err.Error()
+	}
+	if err := os.Remove(entry.Backup); err != nil {
+		return revertFailed, err.Error()
+	}
+	return revertRestored, entry.Encoding + " from " + entry.Backup
+}
+
+func runRevert(c *cli, args []string) int {
+	var isDryRun bool
+
+	fs := c.newFlagSet("revert")
+	fs.BoolVar(&isDryRun, "dry-run", false, "only lists what would be restored")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 1 {
+		return c.fail(exitUsage, "no journal mentioned. use 'utfcoder revert journal', the file batch -journal wrote")
+	}
+
+	entries, _, err := readJournal(fs.Arg(0))
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	// the last entry of a file is what it was converted to last, the files are reverted newest first
+	reverted := map[string]bool{}
+	counts := map[string]int{}
+	for idx := len(entries) - 1; idx >= 0; idx -= 1 {
+		entry := entries[idx]
+		if reverted[entry.Target] {
+			continue
+		}
+		reverted[entry.Target] = true
+
+		status, detail := revertEntry(entry, isDryRun)
+		fmt.Fprintf(c.stdout, "%v: %v (%v)\n", entry.Target, status, detail)
+		counts[status] += 1
+	}
+
+	var summary []string
+	for _, status := range []string{revertRestored, revertRemoved, revertUnchanged, revertFailed} {
+		if count := counts[status]; count > 0 {
+			summary = append(summary, fmt.Sprint(count, " ", status))
+		}
+	}
+	fmt.Fprintf(c.stdout, "%v files: %v\n", len(reverted), strings.Join(summary, ", "))
+
+	if counts[revertFailed] > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
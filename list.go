@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"utfcoder/escape"
+	"utfcoder/types"
+)
+
+func joinNames[T ~string](names []T) string {
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, string(name))
+	}
+	return strings.Join(parts, " ")
+}
+
+func runList(c *cli, args []string) int {
+	fs := c.newFlagSet("list")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	fmt.Fprintln(c.stdout, "unicode encodings (-from, -to):", joinNames(validEncodings[:]))
+	fmt.Fprintln(c.stdout, "escaped flavors (-from, -to):", joinNames(escape.Flavors[:]))
+	fmt.Fprintln(c.stdout, "character references (-from, -to):", joinNames([]string{types.HTML_ENTITIES, types.XML_CHARREF}))
+	fmt.Fprintln(c.stdout, "text notations (-from):", joinNames([]string{types.CODEPOINTS, types.PERCENT}))
+	fmt.Fprintln(c.stdout, "charsets of character references (-charset):", joinNames(validCharsets[:]))
+	fmt.Fprintln(c.stdout, "fallback charsets (-fallback):", joinNames(validFallbacks[:]))
+	fmt.Fprintln(c.stdout, "error modes (-errors):", joinNames(validErrorModes[:]))
+	fmt.Fprintln(c.stdout, "escape scopes (-escape):", joinNames(validEscapeScopes[:]))
+	fmt.Fprintln(c.stdout, "xml checks (-xml-check):", joinNames(validXMLChecks[:]))
+	fmt.Fprintln(c.stdout, "normalization forms (-normalize):", joinNames(validNormalizationForms[:]))
+	fmt.Fprintln(c.stdout, "line endings (-eol):", joinNames(validLineEndings[:]))
+
+	return exitOK
+}
@@ -1,16 +1,22 @@
 package logger
 
 import (
-	"flag"
 	"fmt"
+	"io"
 	"os"
 )
 
-var verbose = flag.Bool("verbose", false, "")
+var verbose bool
+var output io.Writer = os.Stdout
+
+// turns the debugging logs on or off and sets where they are written
+func Init(isVerbose bool, w io.Writer) {
+	verbose, output = isVerbose, w
+}
 
 func Log(items ...any) {
-	if *verbose {
-		fmt.Println(items...)
+	if verbose {
+		fmt.Fprintln(output, items...)
 	}
 }
 
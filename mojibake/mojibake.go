@@ -0,0 +1,184 @@
+package mojibake
+
+import (
+	"unicode/utf8"
+	"utfcoder/charset"
+	"utfcoder/types"
+	UTF8 "utfcoder/utf8"
+)
+
+// text encoded more often than this is left alone
+const maxLayers = 4
+
+// Repair describes a line whose mojibake was undone
+type Repair struct {
+	// 1-based line number
+	Line int
+	// charset every undone layer had been misread as, outermost first
+	Layers        []string
+	Before, After []uint32
+}
+
+// returns the byte a code point came from when utf-8 was misread as windows-1252 or latin-1
+func misreadByte(bits uint32) (byte, bool) {
+	if b, ok := charset.EncodeCodepoint(bits, types.WINDOWS_1252); ok {
+		return b, true
+	}
+	// latin-1 reads 0x80-0x9F as C1 controls where windows-1252 has characters
+	return charset.EncodeCodepoint(bits, types.LATIN_1)
+}
+
+// returns the number of continuation bytes a utf-8 lead byte announces, 0 if b isn't a lead byte
+func continuationBytes(b byte) int {
+	if b >= 0xC2 && b <= 0xDF {
+		return 1
+	} else if b >= 0xE0 && b <= 0xEF {
+		return 2
+	} else if b >= 0xF0 && b <= 0xF4 {
+		return 3
+	}
+	return 0
+}
+
+// returns how implausible codepoints look. every misread utf-8 sequence (Ã© for é, â€™ for ’) and every C1 control counts
+func badness(codepoints []uint32) int {
+	var score int
+
+	for i := 0; i < len(codepoints); i += 1 {
+		if codepoints[i] >= 0x80 && codepoints[i] <= 0x9F {
+			score += 1
+			continue
+		}
+
+		b, ok := misreadByte(codepoints[i])
+		size := continuationBytes(b)
+		if !ok || size == 0 || i+size >= len(codepoints) {
+			continue
+		}
+
+		isSequence := true
+		for j := 1; j <= size; j += 1 {
+			if c, ok := misreadByte(codepoints[i+j]); !ok || c&0xc0 != 0x80 {
+				isSequence = false
+				break
+			}
+		}
+
+		if isSequence {
+			score += 1
+			i += size
+		}
+	}
+
+	return score
+}
+
+// returns codepoints with one layer of misreading undone and the charset it was misread as, false if codepoints
+// can't have come from misread utf-8
+func undoLayer(codepoints []uint32) ([]uint32, string, bool) {
+	var bytes = make([]byte, 0, len(codepoints))
+	misreadAs := types.LATIN_1
+
+	for _, bits := range codepoints {
+		b, ok := misreadByte(bits)
+		if !ok {
+			return nil, "", false
+		}
+		if bits != uint32(b) {
+			misreadAs = types.WINDOWS_1252
+		}
+		bytes = append(bytes, b)
+	}
+
+	if !utf8.Valid(bytes) {
+		return nil, "", false
+	}
+
+	repaired, _, _ := UTF8.Decode(bytes, types.REPLACE)
+	return repaired, misreadAs, true
+}
+
+// returns a run of non-ascii code points with every layer of mojibake undone, as long as each layer makes it more
+// plausible, and the charsets undone
+func fixRun(codepoints []uint32) ([]uint32, []string) {
+	var layers []string
+
+	current, score := codepoints, badness(codepoints)
+	for score > 0 && len(layers) < maxLayers {
+		candidate, misreadAs, ok := undoLayer(current)
+		if !ok {
+			break
+		}
+
+		candidateScore := badness(candidate)
+		if candidateScore >= score {
+			break
+		}
+
+		current, score = candidate, candidateScore
+		layers = append(layers, misreadAs)
+	}
+
+	return current, layers
+}
+
+// returns a line with the mojibake of every run of non-ascii code points undone, and the charsets undone. ascii reads
+// the same in every layer, so the runs are repaired one by one and genuine text next to mojibake stays as it is. a
+// layer counts as windows-1252 when any run needed it
+func FixLine(codepoints []uint32) ([]uint32, []string) {
+	var output = make([]uint32, 0, len(codepoints))
+	var layers []string
+
+	for start := 0; start < len(codepoints); {
+		if codepoints[start] < 0x80 {
+			output = append(output, codepoints[start])
+			start += 1
+			continue
+		}
+
+		end := start
+		for end < len(codepoints) && codepoints[end] >= 0x80 {
+			end += 1
+		}
+
+		fixed, runLayers := fixRun(codepoints[start:end])
+		output = append(output, fixed...)
+		for idx, misreadAs := range runLayers {
+			if idx == len(layers) {
+				layers = append(layers, misreadAs)
+			} else if misreadAs == types.WINDOWS_1252 {
+				layers[idx] = misreadAs
+			}
+		}
+		start = end
+	}
+
+	return output, layers
+}
+
+// returns codepoints with the mojibake of every line undone and a report of the lines changed
+func Fix(codepoints []uint32) ([]uint32, []Repair) {
+	var output = make([]uint32, 0, len(codepoints))
+	var repairs []Repair
+
+	line := 1
+	for start := 0; start < len(codepoints); line += 1 {
+		end := start
+		for end < len(codepoints) && codepoints[end] != '\n' {
+			end += 1
+		}
+
+		fixed, layers := FixLine(codepoints[start:end])
+		if len(layers) != 0 {
+			repairs = append(repairs, Repair{Line: line, Layers: layers, Before: codepoints[start:end], After: fixed})
+		}
+		output = append(output, fixed...)
+
+		if end < len(codepoints) {
+			output = append(output, '\n')
+		}
+		start = end + 1
+	}
+
+	return output, repairs
+}
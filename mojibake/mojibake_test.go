@@ -0,0 +1,69 @@
+package mojibake
+
+import (
+	"slices"
+	"testing"
+	"utfcoder/types"
+)
+
+func codepointsOf(s string) []uint32 {
+	var codepoints []uint32
+	for _, r := range s {
+		codepoints = append(codepoints, uint32(r))
+	}
+	return codepoints
+}
+
+func TestFixLine(t *testing.T) {
+	for _, test := range fixLineTestInputs {
+		output, layers := FixLine(codepointsOf(test.input))
+
+		if !slices.Equal(output, codepointsOf(test.expected)) || !slices.Equal(layers, test.layers) {
+			t.Errorf(`FixLine(%q) = output=%q, layers=%v, Expected = output=%q, layers=%v`, test.input, string(runesOf(output)), layers, test.expected, test.layers)
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	input := codepointsOf("ok\nCafÃ©\r\nitâ€™s\n")
+	expected := codepointsOf("ok\nCafé\r\nit’s\n")
+
+	output, repairs := Fix(input)
+
+	if !slices.Equal(output, expected) {
+		t.Errorf(`Fix(%q) = output=%q, Expected = output=%q`, string(runesOf(input)), string(runesOf(output)), string(runesOf(expected)))
+	}
+	if len(repairs) != 2 || repairs[0].Line != 2 || repairs[1].Line != 3 {
+		t.Errorf(`Fix(%q) = repairs=%v, Expected = repairs of lines 2 and 3`, string(runesOf(input)), repairs)
+	}
+}
+
+func runesOf(codepoints []uint32) []rune {
+	var runes []rune
+	for _, bits := range codepoints {
+		runes = append(runes, rune(bits))
+	}
+	return runes
+}
+
+var fixLineTestInputs = []struct {
+	input    string
+	expected string
+	layers   []string
+}{
+	{"CafÃ©", "Café", []string{types.LATIN_1}},
+	{"itâ€™s", "it’s", []string{types.WINDOWS_1252}},
+	{"itâ\u0080\u0099s", "it’s", []string{types.LATIN_1}},
+	{"CafÃƒÂ©", "Café", []string{types.WINDOWS_1252, types.LATIN_1}},
+	{"â€œquotedâ€\u009d", "“quoted”", []string{types.WINDOWS_1252}},
+	{"Â© 2024", "© 2024", []string{types.LATIN_1}},
+	// every run is repaired on its own, next to genuine text
+	{"CafÃ© Müller", "Café Müller", []string{types.LATIN_1}},
+	{"naïve, itâ€™s Ã©tÃ©", "naïve, it’s été", []string{types.WINDOWS_1252}},
+	{"Ã©tÃƒÂ©", "été", []string{types.WINDOWS_1252, types.LATIN_1}},
+	// genuine text stays as it is
+	{"Café", "Café", nil},
+	{"naïve – déjà vu", "naïve – déjà vu", nil},
+	{"Ã la carte", "Ã la carte", nil},
+	{"日本語", "日本語", nil},
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// files have no unix owner here, so there is none to keep
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
@@ -1,8 +1,11 @@
 package main
 
-import "utfcoder/logger"
-
-var fatal = logger.Fatal
+import (
+	"errors"
+	"fmt"
+	"utfcoder/escape"
+	"utfcoder/types"
+)
 
 func isValidEncoding(pEncoding string) bool {
 	for _, encoding := range validEncodings {
@@ -13,20 +16,158 @@ func isValidEncoding(pEncoding string) bool {
 	return false
 }
 
-func RunPrechecks() {
-	if len(sourceFile) == 0 {
-		fatal("no source file path mentioned. use '-s filepath/filename' to mention source file path")
+func isValidSourceEncoding(pEncoding string) bool {
+	return isValidEncoding(pEncoding) || escape.IsSourceEncoding(pEncoding)
+}
+
+// returns whether the code points of a source in pEncoding can be traced back to its bytes, which escaped text can't
+func isLocatableEncoding(pEncoding string) bool {
+	return pEncoding == types.AUTO || isValidEncoding(pEncoding)
+}
+
+func isValidTargetEncoding(pEncoding string) bool {
+	return isValidEncoding(pEncoding) || escape.IsFlavor(pEncoding) || escape.IsReferenceEncoding(pEncoding)
+}
+
+func isValidCharset(pCharset string) bool {
+	for _, charset := range validCharsets {
+		if charset == pCharset {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidFallback(pFallback string) bool {
+	for _, fallback := range validFallbacks {
+		if fallback == pFallback {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidXMLCheck(pXMLCheck types.XMLCheck) bool {
+	for _, check := range validXMLChecks {
+		if check == pXMLCheck {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidEscapeScope(pEscapeScope types.EscapeScope) bool {
+	for _, scope := range validEscapeScopes {
+		if scope == pEscapeScope {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidNormalizationForm(pForm types.NormalizationForm) bool {
+	for _, form := range validNormalizationForms {
+		if form == pForm {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidLineEnding(pLineEnding types.LineEnding) bool {
+	for _, lineEnding := range validLineEndings {
+		if lineEnding == pLineEnding {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidErrorMode(pErrorMode types.ErrorMode) bool {
+	for _, mode := range validErrorModes {
+		if mode == pErrorMode {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the first problem with the flags of a converting command
+func RunPrechecks(cfg *config) error {
+	if len(cfg.sourceFile) == 0 && !cfg.isBatch {
+		return errors.New("no source file path mentioned. use '-s filepath/filename' to mention source file path")
+	}
+
+	// batch detects the encoding of every file
+	isAuto := cfg.isBatch && cfg.fromEncoding == types.AUTO
+	if !isAuto && (len(cfg.fromEncoding) == 0 || !isValidSourceEncoding(cfg.fromEncoding)) {
+		return errors.New("no (or) invalid source encoding provided. use '-from utf-8/utf-16/utf-32'")
+	}
+
+	if len(cfg.toEncoding) == 0 || !isValidTargetEncoding(cfg.toEncoding) {
+		return errors.New("no (or) invalid target encoding provided. use '-to utf-8/utf-16/utf-32'")
+	}
+
+	if len(cfg.errorMode) != 0 && !isValidErrorMode(cfg.errorMode) {
+		return errors.New("invalid error mode provided. use '-errors replace/surrogateescape'")
+	}
+
+	if len(cfg.escapeScope) != 0 && !isValidEscapeScope(cfg.escapeScope) {
+		return errors.New("invalid escape scope provided. use '-escape nonascii/nonprintable'")
+	}
+
+	if len(cfg.charset) != 0 && !isValidCharset(cfg.charset) {
+		return errors.New("invalid charset provided. use '-charset ascii/latin-1'")
 	}
 
-	if len(fromEncoding) == 0 || !isValidEncoding(fromEncoding) {
-		fatal("no (or) invalid source encoding provided. use '-from utf-8/utf-16/utf-32'")
+	if len(cfg.fallback) != 0 && !isValidFallback(cfg.fallback) {
+		return errors.New("invalid fallback charset provided. use '-fallback windows-1252/latin-1/shift_jis/utf-16le/utf-16be'")
 	}
 
-	if len(toEncoding) == 0 || !isValidEncoding(toEncoding) {
-		fatal("no (or) invalid target encoding provided. use '-to utf-8/utf-16/utf-32'")
+	if len(cfg.fallback) != 0 && cfg.fromEncoding != types.UTF_8 {
+		return errors.New("a fallback charset only applies to utf-8 sources. use '-from utf-8'")
 	}
 
-	if fromEncoding == toEncoding {
-		fatal("incorrect source/target encoding provided. cannot encode", fromEncoding, "again to", toEncoding)
+	if len(cfg.xmlCheck) != 0 && !isValidXMLCheck(cfg.xmlCheck) {
+		return errors.New("invalid xml check provided. use '-xml-check flag/remove'")
 	}
+
+	if len(cfg.normalizationForm) != 0 && !isValidNormalizationForm(cfg.normalizationForm) {
+		return errors.New("invalid normalization form provided. use '-normalize nfc/nfd/nfkc/nfkd'")
+	}
+
+	if len(cfg.lineEnding) != 0 && !isValidLineEnding(cfg.lineEnding) {
+		return errors.New("invalid line ending provided. use '-eol lf/crlf/cr/keep'")
+	}
+
+	if cfg.isInPlace && (len(cfg.targetFile) != 0 || cfg.sourceFile == "-") {
+		return errors.New("in place conversion writes over the source file. use '-in-place' without '-t' and with a source file other than stdin")
+	}
+
+	if cfg.isInPlace && cfg.isSplit {
+		return errors.New("split writes numbered parts, not the source file. use '-t' to name the parts instead of '-in-place'")
+	}
+
+	if cfg.isFollow && !isValidEncoding(cfg.fromEncoding) {
+		return errors.New("follow only reads utf-8, utf-16 and utf-32 sources. use '-from utf-8/utf-16/utf-32'")
+	}
+
+	if cfg.isFollow && (cfg.isInPlace || cfg.isSplit || len(cfg.fallback) != 0 || cfg.maxBytes > 0) {
+		return errors.New("follow converts a line at a time as the source grows. use '-follow' without '-in-place', '-fallback', '-max-bytes' or split")
+	}
+
+	if len(cfg.backupSuffix) != 0 && !cfg.isInPlace {
+		return errors.New("a backup is only kept of a source file converted in place. use '-backup' with '-in-place'")
+	}
+
+	if cfg.isSplit && cfg.maxBytes <= 0 {
+		return errors.New("no part size provided. use '-max-bytes N' or '-max-units N' to mention the size of every part")
+	}
+
+	// encoding to the same encoding only makes sense when the text itself is changed on the way
+	if cfg.fromEncoding == cfg.toEncoding && !cfg.hasTextStage() {
+		return fmt.Errorf("incorrect source/target encoding provided. cannot encode %v again to %v", cfg.fromEncoding, cfg.toEncoding)
+	}
+
+	return nil
 }
@@ -5,67 +5,73 @@ import (
 	"testing"
 )
 
-func MockFatal() bool {
-	return true
-}
-
 func TestNoSourceFileRunPrechecks(t *testing.T) {
-	var fatalMessage = ""
-	sourceFile, targetFile, fromEncoding, toEncoding = "", "file2", "utf-32", "utf-8"
-	fatal = func(items ...any) {
-		fatalMessage = items[0].(string)
-	}
-	expectedFatalMessage := "no source file path mentioned. use '-s filepath/filename' to mention source file path"
+	cfg := config{sourceFile: "", targetFile: "file2", fromEncoding: "utf-32", toEncoding: "utf-8"}
+	expectedError := "no source file path mentioned. use '-s filepath/filename' to mention source file path"
 
-	RunPrechecks()
-	if fatalMessage != expectedFatalMessage {
-		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, fatalMessage, expectedFatalMessage)
+	if err := RunPrechecks(&cfg); err == nil || err.Error() != expectedError {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, err, expectedError)
 	}
 }
 
 func TestNoFromEncodingRunPrechecks(t *testing.T) {
-	var fatalMessage = ""
-	sourceFile, targetFile, fromEncoding, toEncoding = "file", "file2", "", "utf-8"
-	fatal = func(items ...any) {
-		fatalMessage = items[0].(string)
-	}
-	expectedFatalMessage := "no (or) invalid source encoding provided. use '-from utf-8/utf-16/utf-32'"
+	cfg := config{sourceFile: "file", targetFile: "file2", fromEncoding: "", toEncoding: "utf-8"}
+	expectedError := "no (or) invalid source encoding provided. use '-from utf-8/utf-16/utf-32'"
 
-	RunPrechecks()
-	if fatalMessage != expectedFatalMessage {
-		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, fatalMessage, expectedFatalMessage)
+	if err := RunPrechecks(&cfg); err == nil || err.Error() != expectedError {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, err, expectedError)
 	}
 }
 
 func TestNoToEncodingRunPrechecks(t *testing.T) {
-	var fatalMessage = ""
-	sourceFile, targetFile, fromEncoding, toEncoding = "file", "file2", "utf-32", ""
-	fatal = func(items ...any) {
-		fatalMessage = items[0].(string)
-	}
-	expectedFatalMessage := "no (or) invalid target encoding provided. use '-to utf-8/utf-16/utf-32'"
+	cfg := config{sourceFile: "file", targetFile: "file2", fromEncoding: "utf-32", toEncoding: ""}
+	expectedError := "no (or) invalid target encoding provided. use '-to utf-8/utf-16/utf-32'"
 
-	RunPrechecks()
-	if fatalMessage != expectedFatalMessage {
-		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, fatalMessage, expectedFatalMessage)
+	if err := RunPrechecks(&cfg); err == nil || err.Error() != expectedError {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, err, expectedError)
 	}
 }
 
 func TestSameFromToEncodingRunPrechecks(t *testing.T) {
-	var fatalMessage = ""
-	sourceFile, targetFile, fromEncoding, toEncoding = "file", "file2", "utf-32", "utf-32"
-	fatal = func(items ...any) {
-		for idx, item := range items {
-			fatalMessage += item.(string)
-			if idx != len(items)-1 {
-				fatalMessage += " "
-			}
-		}
+	cfg := config{sourceFile: "file", targetFile: "file2", fromEncoding: "utf-32", toEncoding: "utf-32"}
+	expectedError := fmt.Sprintf("incorrect source/target encoding provided. cannot encode %v again to %v", cfg.fromEncoding, cfg.toEncoding)
+
+	if err := RunPrechecks(&cfg); err == nil || err.Error() != expectedError {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, err, expectedError)
+	}
+}
+
+func TestInvalidErrorModeRunPrechecks(t *testing.T) {
+	cfg := config{sourceFile: "file", targetFile: "file2", fromEncoding: "utf-8", toEncoding: "utf-16", errorMode: "ignore"}
+	expectedError := "invalid error mode provided. use '-errors replace/surrogateescape'"
+
+	if err := RunPrechecks(&cfg); err == nil || err.Error() != expectedError {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, err, expectedError)
 	}
-	expectedFatalMessage := fmt.Sprintf("incorrect source/target encoding provided. cannot encode %v again to %v", fromEncoding, toEncoding)
+}
+
+func TestBackupWithoutInPlaceRunPrechecks(t *testing.T) {
+	cfg := config{sourceFile: "file", targetFile: "file2", fromEncoding: "utf-8", toEncoding: "utf-16", backupSuffix: ".orig"}
+	expectedError := "a backup is only kept of a source file converted in place. use '-backup' with '-in-place'"
+
+	if err := RunPrechecks(&cfg); err == nil || err.Error() != expectedError {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, err, expectedError)
+	}
+}
+
+func TestInPlaceWithTargetRunPrechecks(t *testing.T) {
+	cfg := config{sourceFile: "file", targetFile: "file2", fromEncoding: "utf-8", toEncoding: "utf-16", isInPlace: true}
+	expectedError := "in place conversion writes over the source file. use '-in-place' without '-t' and with a source file other than stdin"
+
+	if err := RunPrechecks(&cfg); err == nil || err.Error() != expectedError {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, err, expectedError)
+	}
+}
+
+func TestValidRunPrechecks(t *testing.T) {
+	cfg := config{sourceFile: "file", fromEncoding: "utf-8", toEncoding: "utf-16"}
 
-	RunPrechecks()
-	if fatalMessage != expectedFatalMessage {
-		t.Errorf(`RunPrechecks() = error=%v, Expected = error=%v`, fatalMessage, expectedFatalMessage)
+	if err := RunPrechecks(&cfg); err != nil {
+		t.Errorf(`RunPrechecks() = error=%v, Expected = error=<nil>`, err)
 	}
 }
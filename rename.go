@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"unicode"
+	"unicode/utf8"
+	"utfcoder/codec"
+	"utfcoder/logger"
+	"utfcoder/types"
+)
+
+// the charsets rename reads names which aren't utf-8 as. auto guesses between the first two, latin-1 only differs
+// from windows-1252 in the controls 0x80-0x9F
+var renameCharsets = []string{types.WINDOWS_1252, types.SHIFT_JIS, types.LATIN_1}
+
+// what rename did with a name
+const (
+	renameRenamed     = "renamed"
+	renameWouldRename = "would rename"
+	renameUnchanged   = "unchanged"
+	renameFailed      = "failed"
+)
+
+// returns the script of a letter, kana counted as han like the japanese names mixing them. empty for the letters
+// common to every script, like the prolonged sound mark
+func letterScript(r rune) string {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+		return "Han"
+	}
+	for name, table := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// returns how unlikely a decoded name is: the undecodable bytes, the controls and the symbols beyond ascii, and every
+// letter next to a letter of another script. names misread in the wrong charset are full of all of them
+func implausibility(codepoints []uint32) int {
+	count := 0
+	previous := ""
+	for _, bits := range codepoints {
+		r := rune(bits)
+		if r == utf8.RuneError || unicode.IsControl(r) || (r >= 0x80 && !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsSpace(r)) {
+			count += 1
+		}
+		if !unicode.IsLetter(r) {
+			previous = ""
+			continue
+		}
+		if script := letterScript(r); len(script) != 0 {
+			if len(previous) != 0 && script != previous {
+				count += 1
+			}
+			previous = script
+		}
+	}
+	return count
+}
+
+// returns the code points of a file name which isn't utf-8 decoded as charset
+func decodeName(name string, charset string) []uint32 {
+	codepoints, _, _ := codec.Decode([]byte(name), types.UTF_8, codec.Options{Fallback: charset, ErrorMode: types.REPLACE})
+	return codepoints
+}
+
+// returns the charset a file name which isn't utf-8 reads most plausibly in, windows-1252 when they tie
+func guessNameCharset(name string) string {
+	best, bestScore := "", 0
+	for _, charset := range renameCharsets[:2] {
+		if score := implausibility(decodeName(name, charset)); len(best) == 0 || score < bestScore {
+			best, bestScore = charset, score
+		}
+	}
+	return best
+}
+
+// renames path to target without ever replacing what another process put there in the meantime. a file is linked
+// at target and unlinked at path, which fails when target exists. a directory, or a file on a file system without
+// hard links, first claims target with an empty directory or file of its own, which only the rename replaces
+func renameNoReplace(path, target string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		err := os.Link(path, target)
+		if err == nil {
+			return os.Remove(path)
+		}
+		if errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+
+	if info.IsDir() {
+		err = os.Mkdir(target, 0700)
+	} else if claim, claimErr := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); claimErr == nil {
+		err = claim.Close()
+	} else {
+		err = claimErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// os.Rename refuses any directory at target, the empty one claimed is replaced like posix allows
+	if err := syscall.Rename(path, target); err != nil {
+		os.Remove(target)
+		return &os.LinkError{Op: "rename", Old: path, New: target, Err: err}
+	}
+	return nil
+}
+
+// renamer holds the flags of the rename command
+type renamer struct {
+	fromCharset     string
+	isNFC, isDryRun bool
+	// the paths names were renamed to in this run, so a dry run finds the collisions a real run would
+	claimed map[string]bool
+}
+
+// returns the utf-8 name of a file and what it was converted from. names which are utf-8 already are only normalized
+func (r *renamer) convertName(name string) (string, []string, error) {
+	var details []string
+	codepoints := decodeName(name, "")
+	if !utf8.ValidString(name) {
+		charset := r.fromCharset
+		if charset == types.AUTO {
+			charset = guessNameCharset(name)
+		}
+		codepoints = decodeName(name, charset)
+		// a U+FFFD which wasn't in the name would lose its bytes for good
+		if slices.Contains(codepoints, utf8.RuneError) {
+			return name, nil, fmt.Errorf("not %v, use another '-from'", charset)
+		}
+		details = append(details, charset)
+	}
+
+	if r.isNFC {
+		normalized := codec.Apply(codepoints, codec.Options{Normalize: types.NFC}, &codec.Report{})
+		if !slices.Equal(normalized, codepoints) {
+			details = append(details, string(types.NFC))
+		}
+		codepoints = normalized
+	}
+
+	output, err := codec.Encode(codepoints, types.UTF_8, codec.Options{ErrorMode: types.REPLACE})
+	if err != nil {
+		return name, nil, err
+	}
+	return string(output), details, nil
+}
+
+// renames the file at path to its utf-8 name, returns the status and what the name was converted from, or the error
+func (r *renamer) rename(path string) (string, string, string) {
+	dir, name := filepath.Split(path)
+	newName, details, err := r.convertName(name)
+	if err != nil {
+		return renameFailed, "", err.Error()
+	}
+	if newName == name {
+		return renameUnchanged, "", ""
+	}
+
+	// a file which already has the name is never overwritten, unless it is the same file under a name the file
+	// system takes as equal
+	target := filepath.Join(dir, newName)
+	if _, err := os.Lstat(target); r.claimed[target] || (err == nil && !isSameFile(path, target)) {
+		return renameFailed, newName, fmt.Sprintf("%q exists", newName)
+	}
+	r.claimed[target] = true
+
+	if r.isDryRun {
+		return renameWouldRename, newName, strings.Join(details, ", ")
+	}
+	// a name the file system takes as equal is the file itself, only then may the rename replace it
+	rename := renameNoReplace
+	if isSameFile(path, target) {
+		rename = os.Rename
+	}
+	if err := rename(path, target); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return renameFailed, newName, fmt.Sprintf("%q exists", newName)
+		}
+		return renameFailed, newName, err.Error()
+	}
+	return renameRenamed, newName, strings.Join(details, ", ")
+}
+
+func runRename(c *cli, args []string) int {
+	r := renamer{claimed: map[string]bool{}}
+
+	flags := c.newFlagSet("rename")
+	lowerStringVar(flags, &r.fromCharset, "from", types.AUTO, "`charset` of the names which aren't utf-8, one of windows-1252/shift_jis/latin-1, auto guesses it for every name")
+	flags.BoolVar(&r.isNFC, "nfc", false, "normalizes the names to NFC as well, like those of files from macOS which are NFD")
+	flags.BoolVar(&r.isDryRun, "dry-run", false, "prints what would be renamed without renaming anything")
+	if code, ok := c.parse(flags, args); !ok {
+		return code
+	}
+
+	if flags.NArg() == 0 {
+		return c.fail(exitUsage, "no path mentioned. use 'utfcoder rename [-from charset] [-nfc] [-dry-run] path...'")
+	}
+	if r.fromCharset != types.AUTO && !slices.Contains(renameCharsets, r.fromCharset) {
+		return c.fail(exitUsage, "invalid charset provided. use '-from' with one of", strings.Join(renameCharsets, "/"), "or auto")
+	}
+
+	counts := map[string]int{}
+	for _, root := range flags.Args() {
+		var paths []string
+		err := filepath.WalkDir(filepath.Clean(root), func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			counts[renameFailed] += 1
+			continue
+		}
+
+		// the files of a directory are renamed before it, while their paths still hold its old name
+		slices.Reverse(paths)
+		for _, path := range paths {
+			status, newName, detail := r.rename(path)
+			counts[status] += 1
+			switch status {
+			case renameUnchanged:
+				logger.Log(path, "is unchanged")
+			case renameFailed:
+				fmt.Fprintf(c.stdout, "%q: %v (%v)\n", path, status, detail)
+			default:
+				fmt.Fprintf(c.stdout, "%q: %v to %q (%v)\n", path, status, newName, detail)
+			}
+		}
+	}
+
+	var summary []string
+	total := 0
+	for _, status := range []string{renameRenamed, renameWouldRename, renameUnchanged, renameFailed} {
+		total += counts[status]
+		if count := counts[status]; count > 0 || status == renameRenamed && !r.isDryRun {
+			summary = append(summary, fmt.Sprint(count, " ", status))
+		}
+	}
+	fmt.Fprintf(c.stdout, "%v names: %v\n", total, strings.Join(summary, ", "))
+
+	if counts[renameFailed] > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"utfcoder/types"
+)
+
+func TestGuessNameCharset(t *testing.T) {
+	for _, test := range guessNameCharsetTestInputs {
+		if output := guessNameCharset(test.name); output != test.expected {
+			t.Errorf(`guessNameCharset(%q) = %v, Expected = %v`, test.name, output, test.expected)
+		}
+	}
+}
+
+func TestRenameNoReplace(t *testing.T) {
+	dir := t.TempDir()
+	file, taken, directory := filepath.Join(dir, "caf\xe9.txt"), filepath.Join(dir, "café.txt"), filepath.Join(dir, "Pr\xe9sentations")
+	os.WriteFile(file, []byte("old"), 0644)
+	os.WriteFile(taken, []byte("new"), 0644)
+	os.Mkdir(directory, 0755)
+	os.WriteFile(filepath.Join(directory, "notes.txt"), []byte("kept"), 0644)
+
+	// a name which appeared after the check is never replaced, neither by a file nor by a directory
+	if err := renameNoReplace(file, taken); !errors.Is(err, fs.ErrExist) {
+		t.Errorf(`renameNoReplace(%q, %q) = err=%v, Expected = err=%v`, file, taken, err, fs.ErrExist)
+	}
+	if err := renameNoReplace(directory, taken); !errors.Is(err, fs.ErrExist) {
+		t.Errorf(`renameNoReplace(%q, %q) = err=%v, Expected = err=%v`, directory, taken, err, fs.ErrExist)
+	}
+	if data, _ := os.ReadFile(taken); string(data) != "new" {
+		t.Errorf(`renameNoReplace(%q) = café.txt=%q, Expected = café.txt="new"`, file, data)
+	}
+
+	for _, test := range [][2]string{{file, filepath.Join(dir, "cafe.txt")}, {directory, filepath.Join(dir, "Présentations")}} {
+		if err := renameNoReplace(test[0], test[1]); err != nil {
+			t.Errorf(`renameNoReplace(%q, %q) = err=%v, Expected = err=<nil>`, test[0], test[1], err)
+		}
+		if _, err := os.Lstat(test[0]); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf(`renameNoReplace(%q, %q) = source err=%v, Expected = source gone`, test[0], test[1], err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "Présentations", "notes.txt")); string(data) != "kept" {
+		t.Errorf(`renameNoReplace(%q) = notes.txt=%q, Expected = notes.txt="kept"`, directory, data)
+	}
+}
+
+var guessNameCharsetTestInputs = []struct {
+	name     string
+	expected string
+}{
+	// Ärger.txt, Résumé.doc, café and Müller.pdf in windows-1252
+	{"\xc4rger.txt", types.WINDOWS_1252},
+	{"R\xe9sum\xe9.doc", types.WINDOWS_1252},
+	{"caf\xe9", types.WINDOWS_1252},
+	{"M\xfcller.pdf", types.WINDOWS_1252},
+	{"\x93Angebot\x94 \x96 Entwurf", types.WINDOWS_1252},
+	// 日本.txt, データ.csv, 漢字 and halfwidth ﾃｽﾄ in shift_jis
+	{"\x93\xfa\x96\x7b.txt", types.SHIFT_JIS},
+	{"\x83\x66\x81\x5b\x83\x5e.csv", types.SHIFT_JIS},
+	{"\x8a\xbf\x8e\x9a", types.SHIFT_JIS},
+	{"\xb3\xbd\xc4", types.SHIFT_JIS},
+	{"\x8e\x91\x97\xbf_2003.xls", types.SHIFT_JIS},
+}
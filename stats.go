@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"utfcoder/codec"
+	"utfcoder/transform"
+	"utfcoder/types"
+	"utfcoder/utils"
+)
+
+// stats are the counts stats prints about a decoded file
+type stats struct {
+	ascii, bmp, astral, escaped int
+	lines                       int
+}
+
+func countCodepoints(codepoints []uint32) stats {
+	var s stats
+	for _, bits := range codepoints {
+		if utils.IsEscapedByte(bits) {
+			s.escaped += 1
+		} else if bits < 0x80 {
+			s.ascii += 1
+		} else if bits < 0x10000 {
+			s.bmp += 1
+		} else {
+			s.astral += 1
+		}
+	}
+	return s
+}
+
+func runStats(c *cli, args []string) int {
+	var sourceFile, fromEncoding string
+
+	fs := c.newFlagSet("stats")
+	sourceFlags(fs, &sourceFile, &fromEncoding)
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	sourceFile, err := sourceArgument(fs, sourceFile)
+	if err != nil {
+		return c.fail(exitUsage, err)
+	}
+
+	data, err := c.readSource(sourceFile)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	codepoints, _, encoding, err := decodeSource(data, fromEncoding, codec.Options{ErrorMode: types.SURROGATE_ESCAPE})
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	s := countCodepoints(codepoints)
+	_, lineEndings := transform.ConvertLineEndings(codepoints, types.EOL_KEEP)
+	for _, count := range lineEndings {
+		s.lines += count
+	}
+	// a last line without a line end is a line too
+	if len(codepoints) != 0 && !transform.IsLineEnding(codepoints[len(codepoints)-1]) {
+		s.lines += 1
+	}
+
+	fmt.Fprintln(c.stdout, "encoding:", encoding)
+	fmt.Fprintln(c.stdout, "byte order mark:", codec.Detect(data).HasBOM)
+	fmt.Fprintln(c.stdout, "bytes:", len(data))
+	fmt.Fprintln(c.stdout, "code points:", len(codepoints)-s.escaped)
+	fmt.Fprintln(c.stdout, "  ascii:", s.ascii)
+	fmt.Fprintln(c.stdout, "  beyond ascii in the BMP:", s.bmp)
+	fmt.Fprintln(c.stdout, "  beyond the BMP:", s.astral)
+	fmt.Fprintln(c.stdout, "grapheme clusters:", len(transform.GraphemeClusters(codepoints))-s.escaped)
+	fmt.Fprintln(c.stdout, "undecodable bytes:", s.escaped)
+	fmt.Fprintln(c.stdout, "lines:", s.lines)
+	if summary := lineEndingSummary(lineEndings); len(summary) != 0 {
+		fmt.Fprintln(c.stdout, "line endings:", summary)
+	}
+
+	return exitOK
+}
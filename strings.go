@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"utfcoder/codec"
+)
+
+// the classes of code points strings takes, graphic by default
+var stringClasses = []string{"graphic", "print", "ascii"}
+
+// returns whether bits belongs to class. tab counts as graphic, like it does for strings(1)
+func isInClass(bits uint32, class string) bool {
+	r := rune(bits)
+	switch class {
+	case "print":
+		return unicode.IsPrint(r)
+	case "ascii":
+		return r == '\t' || (r >= 0x20 && r <= 0x7E)
+	}
+	// U+FFFD is what broken text decodes to, not text
+	return (unicode.IsGraphic(r) || r == '\t') && r != 0xFFFD
+}
+
+// returns the unicode scripts named in a comma separated list, case insensitively
+func parseScripts(list string) ([]*unicode.RangeTable, error) {
+	var scripts []*unicode.RangeTable
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for scriptName, table := range unicode.Scripts {
+			if strings.EqualFold(scriptName, name) {
+				scripts, found = append(scripts, table), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown script %v. use a unicode script name like Latin, Cyrillic or Han", name)
+		}
+	}
+	return scripts, nil
+}
+
+func runStrings(c *cli, args []string) int {
+	var minLength int
+	var encodingList, class, scriptList string
+
+	fs := c.newFlagSet("strings")
+	fs.IntVar(&minLength, "n", 4, "minimum `length` of a string in characters")
+	fs.StringVar(&encodingList, "e", strings.Join(codec.StringEncodings, ","), "comma separated `encodings` to look for, where strings overlap the longest wins and ties go to the one listed first")
+	lowerStringVar(fs, &class, "class", "graphic", "which characters a string is made of, `class` is one of graphic (letters, marks, numbers, punctuation, symbols, spaces and tab)/print (graphic with no space but U+0020)/ascii (printable ascii and tab)")
+	fs.StringVar(&scriptList, "script", "", "comma separated unicode `scripts` a string may use besides the characters common to all scripts, like Latin,Cyrillic")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 {
+		return c.fail(exitUsage, "no file mentioned. use 'utfcoder strings file...', - reads stdin")
+	}
+	if minLength < 1 {
+		return c.fail(exitUsage, "invalid minimum length provided. use '-n 4'")
+	}
+	if !slices.Contains(stringClasses, class) {
+		return c.fail(exitUsage, "invalid class provided. use one of", strings.Join(stringClasses, "/"))
+	}
+
+	var encodings []string
+	for _, encoding := range strings.Split(strings.ToLower(encodingList), ",") {
+		encoding = strings.TrimSpace(encoding)
+		if !slices.Contains(codec.StringEncodings, encoding) {
+			return c.fail(exitUsage, "invalid encoding", encoding, "provided. use '-e' with some of", strings.Join(codec.StringEncodings, ","))
+		}
+		encodings = append(encodings, encoding)
+	}
+
+	var scripts []*unicode.RangeTable
+	if len(scriptList) != 0 {
+		var err error
+		if scripts, err = parseScripts(scriptList); err != nil {
+			return c.fail(exitUsage, err)
+		}
+		// spaces, digits and punctuation, and the marks which combine with any script
+		scripts = append(scripts, unicode.Common, unicode.Inherited)
+	}
+
+	accept := func(bits uint32) bool {
+		if !isInClass(bits, class) {
+			return false
+		}
+		return len(scripts) == 0 || unicode.IsOneOf(scripts, rune(bits))
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		source, err := c.openSource(path)
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			code = exitFailure
+			continue
+		}
+
+		err = codec.FindStrings(source, encodings, minLength, accept, func(run codec.TextRun) error {
+			_, err := fmt.Fprintf(c.stdout, "%v:%v:%v:%v\n", path, run.Offset, run.Encoding, printableLine(run.Codepoints))
+			return err
+		})
+		source.Close()
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+			code = exitFailure
+		}
+	}
+	return code
+}
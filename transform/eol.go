@@ -0,0 +1,101 @@
+package transform
+
+import "utfcoder/types"
+
+var lineEndingCodepoints = map[types.LineEnding][]uint32{
+	types.EOL_LF:   {'\n'},
+	types.EOL_CRLF: {'\r', '\n'},
+	types.EOL_CR:   {'\r'},
+}
+
+// the order line end styles are reported in
+var LineEndingStyles = [6]types.LineEnding{types.EOL_LF, types.EOL_CRLF, types.EOL_CR, types.EOL_NEL, types.EOL_LS, types.EOL_PS}
+
+// LineEndingConverter rewrites every line end of a code point stream which arrives in chunks and counts the styles
+// it has seen. a CR at the end of a chunk is held back until the next chunk shows whether it is part of a CRLF
+type LineEndingConverter struct {
+	target    types.LineEnding
+	pendingCR bool
+	// number of line ends of every style seen so far
+	Counts map[types.LineEnding]int
+}
+
+func NewLineEndingConverter(target types.LineEnding) *LineEndingConverter {
+	return &LineEndingConverter{target: target, Counts: map[types.LineEnding]int{}}
+}
+
+func (c *LineEndingConverter) appendLineEnding(output []uint32, style types.LineEnding, original []uint32) []uint32 {
+	c.Counts[style] += 1
+
+	if c.target == types.EOL_KEEP || len(c.target) == 0 {
+		return append(output, original...)
+	}
+	return append(output, lineEndingCodepoints[c.target]...)
+}
+
+// returns the converted code points of the stream, except a trailing CR
+func (c *LineEndingConverter) Write(codepoints []uint32) []uint32 {
+	var output = make([]uint32, 0, len(codepoints)+1)
+
+	for _, bits := range codepoints {
+		if c.pendingCR {
+			c.pendingCR = false
+			if bits == '\n' {
+				output = c.appendLineEnding(output, types.EOL_CRLF, []uint32{'\r', '\n'})
+				continue
+			}
+			output = c.appendLineEnding(output, types.EOL_CR, []uint32{'\r'})
+		}
+
+		switch bits {
+		case '\r':
+			c.pendingCR = true
+		case '\n':
+			output = c.appendLineEnding(output, types.EOL_LF, []uint32{bits})
+		case 0x85:
+			output = c.appendLineEnding(output, types.EOL_NEL, []uint32{bits})
+		case 0x2028:
+			output = c.appendLineEnding(output, types.EOL_LS, []uint32{bits})
+		case 0x2029:
+			output = c.appendLineEnding(output, types.EOL_PS, []uint32{bits})
+		default:
+			output = append(output, bits)
+		}
+	}
+
+	return output
+}
+
+// returns the held back CR, at the end of the stream
+func (c *LineEndingConverter) Flush() []uint32 {
+	if !c.pendingCR {
+		return nil
+	}
+
+	c.pendingCR = false
+	return c.appendLineEnding(nil, types.EOL_CR, []uint32{'\r'})
+}
+
+// returns whether bits is a line end or the last code point of one
+func IsLineEnding(bits uint32) bool {
+	return bits == '\r' || bits == '\n' || bits == 0x85 || bits == 0x2028 || bits == 0x2029
+}
+
+// returns whether the counted line ends use more than one style
+func IsMixed(counts map[types.LineEnding]int) bool {
+	styles := 0
+	for _, count := range counts {
+		if count > 0 {
+			styles += 1
+		}
+	}
+	return styles > 1
+}
+
+// returns codepoints with every line end rewritten to target and the number of line ends of every style found
+func ConvertLineEndings(codepoints []uint32, target types.LineEnding) ([]uint32, map[types.LineEnding]int) {
+	converter := NewLineEndingConverter(target)
+	output := append(converter.Write(codepoints), converter.Flush()...)
+
+	return output, converter.Counts
+}
@@ -0,0 +1,62 @@
+package transform
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+// "a" CRLF "b" LF "c" CR "d" NEL "e" LS "f" PS
+var eolTestCodepoints = []uint32{'a', '\r', '\n', 'b', '\n', 'c', '\r', 'd', 0x85, 'e', 0x2028, 'f', 0x2029}
+
+func TestConvertLineEndings(t *testing.T) {
+	for _, test := range eolTestInputs {
+		output, counts := ConvertLineEndings(eolTestCodepoints, test.target)
+
+		if !equalCodepoints(output, test.expected) {
+			t.Errorf(`ConvertLineEndings(%v, %v) = output=%v, Expected = output=%v`, eolTestCodepoints, test.target, output, test.expected)
+		}
+		for _, style := range LineEndingStyles {
+			if counts[style] != 1 {
+				t.Errorf(`ConvertLineEndings(%v, %v) = counts=%v, Expected = one line end of every style`, eolTestCodepoints, test.target, counts)
+				break
+			}
+		}
+		if !IsMixed(counts) {
+			t.Errorf(`IsMixed(%v) = false, Expected = true`, counts)
+		}
+	}
+}
+
+func TestLineEndingConverterChunks(t *testing.T) {
+	expected := []uint32{'a', '\n', 'b', '\n', 'c', '\n', 'd', '\n', 'e', '\n', 'f', '\n'}
+
+	// every split point, including the one between CR and LF, must give the same result
+	for split := 0; split <= len(eolTestCodepoints); split += 1 {
+		converter := NewLineEndingConverter(types.EOL_LF)
+		output := converter.Write(eolTestCodepoints[:split])
+		output = append(output, converter.Write(eolTestCodepoints[split:])...)
+		output = append(output, converter.Flush()...)
+
+		if !equalCodepoints(output, expected) || converter.Counts[types.EOL_CRLF] != 1 || converter.Counts[types.EOL_CR] != 1 {
+			t.Errorf(`LineEndingConverter split at %v = output=%v, counts=%v, Expected = output=%v`, split, output, converter.Counts, expected)
+		}
+	}
+}
+
+func TestTrailingCR(t *testing.T) {
+	output, counts := ConvertLineEndings([]uint32{'a', '\r'}, types.EOL_CRLF)
+
+	if !equalCodepoints(output, []uint32{'a', '\r', '\n'}) || counts[types.EOL_CR] != 1 || IsMixed(counts) {
+		t.Errorf(`ConvertLineEndings(a CR, crlf) = output=%v, counts=%v, Expected = output=%v`, output, counts, []uint32{'a', '\r', '\n'})
+	}
+}
+
+var eolTestInputs = []struct {
+	target   types.LineEnding
+	expected []uint32
+}{
+	{types.EOL_LF, []uint32{'a', '\n', 'b', '\n', 'c', '\n', 'd', '\n', 'e', '\n', 'f', '\n'}},
+	{types.EOL_CRLF, []uint32{'a', '\r', '\n', 'b', '\r', '\n', 'c', '\r', '\n', 'd', '\r', '\n', 'e', '\r', '\n', 'f', '\r', '\n'}},
+	{types.EOL_CR, []uint32{'a', '\r', 'b', '\r', 'c', '\r', 'd', '\r', 'e', '\r', 'f', '\r'}},
+	{types.EOL_KEEP, eolTestCodepoints},
+}
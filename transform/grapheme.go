@@ -0,0 +1,42 @@
+package transform
+
+import (
+	"unicode/utf8"
+	"utfcoder/types"
+	"utfcoder/utils"
+
+	"github.com/rivo/uniseg"
+)
+
+// returns codepoints cut into extended grapheme clusters (UAX #29). surrogate escapes and invalid code points are clusters of their own
+func GraphemeClusters(codepoints []uint32) [][]uint32 {
+	var clusters [][]uint32
+	var text []byte
+
+	for start := 0; start < len(codepoints); {
+		if !isText(codepoints[start]) {
+			clusters = append(clusters, codepoints[start:start+1])
+			start += 1
+			continue
+		}
+
+		end := start
+		text = text[:0]
+		for end < len(codepoints) && isText(codepoints[end]) {
+			text = utils.AppendUTF8(text, codepoints[end], types.REPLACE)
+			end += 1
+		}
+
+		state := -1
+		for len(text) > 0 {
+			var cluster []byte
+			cluster, text, _, state = uniseg.FirstGraphemeCluster(text, state)
+
+			size := utf8.RuneCount(cluster)
+			clusters = append(clusters, codepoints[start:start+size])
+			start += size
+		}
+	}
+
+	return clusters
+}
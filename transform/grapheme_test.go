@@ -0,0 +1,41 @@
+package transform
+
+import "testing"
+
+func TestGraphemeClusters(t *testing.T) {
+	for _, test := range graphemeTestInputs {
+		clusters := GraphemeClusters(test.input)
+
+		var sizes []int
+		for _, cluster := range clusters {
+			sizes = append(sizes, len(cluster))
+		}
+
+		if len(sizes) != len(test.sizes) {
+			t.Errorf(`GraphemeClusters(%v) = sizes=%v, Expected = sizes=%v`, test.input, sizes, test.sizes)
+			continue
+		}
+		for idx := range sizes {
+			if sizes[idx] != test.sizes[idx] {
+				t.Errorf(`GraphemeClusters(%v) = sizes=%v, Expected = sizes=%v`, test.input, sizes, test.sizes)
+				break
+			}
+		}
+	}
+}
+
+// code points and the number of code points of every cluster
+var graphemeTestInputs = []struct {
+	input []uint32
+	sizes []int
+}{
+	{[]uint32{}, nil},
+	{[]uint32{'a', 'b'}, []int{1, 1}},
+	{[]uint32{'e', 0x301, 'x'}, []int{2, 1}},                        // e + combining acute
+	{[]uint32{'\r', '\n', 'a'}, []int{2, 1}},                        // CRLF stays together
+	{[]uint32{0x1F468, 0x200D, 0x1F469, 0x200D, 0x1F467}, []int{5}}, // 👨‍👩‍👧 ZWJ sequence
+	{[]uint32{0x1F44D, 0x1F3FD, '!'}, []int{2, 1}},                  // 👍🏽 with skin tone modifier
+	{[]uint32{0x1F1EB, 0x1F1F7, 0x1F1E9, 0x1F1EA}, []int{2, 2}},     // 🇫🇷🇩🇪 regional indicator pairs
+	{[]uint32{0x1100, 0x1161, 0x11A8, 'a'}, []int{3, 1}},            // hangul jamo syllable
+	{[]uint32{'e', 0xDCE9, 0x301}, []int{1, 1, 1}},                  // a surrogate escape ends the cluster
+}
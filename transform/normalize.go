@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"utfcoder/types"
+	"utfcoder/utils"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// a run of code points without a boundary is normalized once it grows this long, like the stream-safe text format limits it
+const maxSegmentLength = 4096
+
+// the composition and decomposition tables of norm are generated from the UCD and compiled into the binary
+var forms = map[types.NormalizationForm]norm.Form{
+	types.NFC:  norm.NFC,
+	types.NFD:  norm.NFD,
+	types.NFKC: norm.NFKC,
+	types.NFKD: norm.NFKD,
+}
+
+// Normalizer normalizes a code point stream which arrives in chunks. the trailing combining sequence
+// of every chunk is held back until the next chunk shows where it ends
+type Normalizer struct {
+	form    norm.Form
+	pending []uint32
+}
+
+func NewNormalizer(form types.NormalizationForm) *Normalizer {
+	return &Normalizer{form: forms[form]}
+}
+
+// surrogate escapes and invalid code points are not text, they are passed through and end any combining sequence
+func isText(bits uint32) bool {
+	return utils.IsValidUnicodeRange(bits)
+}
+
+func (n *Normalizer) isBoundary(bits uint32) bool {
+	return !isText(bits) || n.form.PropertiesString(string(rune(bits))).BoundaryBefore()
+}
+
+// returns the normalized form of a segment which starts and ends at boundaries
+func (n *Normalizer) normalize(output []uint32, segment []uint32) []uint32 {
+	var text []byte
+
+	for idx, bits := range segment {
+		if isText(bits) {
+			text = utils.AppendUTF8(text, bits, types.REPLACE)
+		}
+
+		if !isText(bits) || idx == len(segment)-1 {
+			for _, r := range string(n.form.Bytes(text)) {
+				output = append(output, uint32(r))
+			}
+			text = text[:0]
+		}
+
+		if !isText(bits) {
+			output = append(output, bits)
+		}
+	}
+
+	return output
+}
+
+// returns the normalized code points of the stream up to the last boundary seen so far
+func (n *Normalizer) Write(codepoints []uint32) []uint32 {
+	n.pending = append(n.pending, codepoints...)
+
+	last := len(n.pending) - 1
+	for last > 0 && !n.isBoundary(n.pending[last]) {
+		last -= 1
+	}
+
+	if last <= 0 && len(n.pending) < maxSegmentLength {
+		return nil
+	}
+	if last <= 0 {
+		last = len(n.pending)
+	}
+
+	output := n.normalize(make([]uint32, 0, last), n.pending[:last])
+	n.pending = append(n.pending[:0], n.pending[last:]...)
+
+	return output
+}
+
+// returns the normalized code points held back, at the end of the stream
+func (n *Normalizer) Flush() []uint32 {
+	output := n.normalize(make([]uint32, 0, len(n.pending)), n.pending)
+	n.pending = n.pending[:0]
+
+	return output
+}
+
+func Normalize(codepoints []uint32, form types.NormalizationForm) []uint32 {
+	normalizer := NewNormalizer(form)
+	return append(normalizer.Write(codepoints), normalizer.Flush()...)
+}
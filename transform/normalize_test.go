@@ -0,0 +1,60 @@
+package transform
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, test := range normalizeTestInputs {
+		output := Normalize(test.input, test.form)
+
+		if !equalCodepoints(output, test.expected) {
+			t.Errorf(`Normalize(%v, %v) = output=%v, Expected = output=%v`, test.input, test.form, output, test.expected)
+		}
+	}
+}
+
+func TestNormalizerChunks(t *testing.T) {
+	for _, test := range normalizeTestInputs {
+		// every split point must give the same result as normalizing the whole input at once
+		for split := 0; split <= len(test.input); split += 1 {
+			normalizer := NewNormalizer(test.form)
+			output := normalizer.Write(test.input[:split])
+			output = append(output, normalizer.Write(test.input[split:])...)
+			output = append(output, normalizer.Flush()...)
+
+			if !equalCodepoints(output, test.expected) {
+				t.Errorf(`Normalizer(%v, %v) split at %v = output=%v, Expected = output=%v`, test.input, test.form, split, output, test.expected)
+			}
+		}
+	}
+}
+
+func equalCodepoints(a []uint32, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+var normalizeTestInputs = []struct {
+	form     types.NormalizationForm
+	input    []uint32
+	expected []uint32
+}{
+	{types.NFC, []uint32{'e', 0x301, 'x'}, []uint32{0xE9, 'x'}},                       // e + combining acute → é
+	{types.NFD, []uint32{0xE9, 'x'}, []uint32{'e', 0x301, 'x'}},                       // é → e + combining acute
+	{types.NFC, []uint32{'a', 0x323, 0x302, 'b'}, []uint32{0x1EAD, 'b'}},              // a + dot below + circumflex → ậ
+	{types.NFC, []uint32{'a', 0x302, 0x323}, []uint32{0x1EAD}},                        // canonical reordering before composition
+	{types.NFC, []uint32{0x1100, 0x1161, 0x11A8}, []uint32{0xAC01}},                   // hangul jamo → 각
+	{types.NFKC, []uint32{0xFB01, 0x2460}, []uint32{'f', 'i', '1'}},                   // ﬁ ligature and circled 1
+	{types.NFKD, []uint32{0x1E9B, 0x323}, []uint32{'s', 0x323, 0x307}},                // long s with dot above
+	{types.NFC, []uint32{'e', 0xDCE9, 0x301}, []uint32{'e', 0xDCE9, 0x301}},           // a surrogate escape ends the sequence
+	{types.NFD, []uint32{0x1F600, 0xAC01}, []uint32{0x1F600, 0x1100, 0x1161, 0x11A8}}, // astral code points pass through
+}
@@ -0,0 +1,41 @@
+package transform
+
+import (
+	"fmt"
+	"utfcoder/types"
+)
+
+// XML 1.0 Char production - #x9 | #xA | #xD | [#x20-#xD7FF] | [#xE000-#xFFFD] | [#x10000-#x10FFFF]
+func IsXMLChar(bits uint32) bool {
+	return bits == 0x9 || bits == 0xA || bits == 0xD ||
+		(bits >= 0x20 && bits <= 0xD7FF) ||
+		(bits >= 0xE000 && bits <= 0xFFFD) ||
+		(bits >= 0x10000 && bits <= 0x10FFFF)
+}
+
+// returns the code points forbidden by XML 1.0 with their position, and codepoints without them when check is remove.
+// the offset of a reported code point is its index in codepoints
+func CheckXML(codepoints []uint32, check types.XMLCheck) ([]uint32, []types.Malformed) {
+	var malformed []types.Malformed
+	var output = codepoints
+	if check == types.XML_REMOVE {
+		output = make([]uint32, 0, len(codepoints))
+	}
+
+	line, column := 1, 1
+	for idx, bits := range codepoints {
+		if !IsXMLChar(bits) {
+			malformed = append(malformed, types.Malformed{Offset: idx, Line: line, Column: column, Reason: fmt.Sprintf("U+%04X is not allowed in XML 1.0", bits)})
+		} else if check == types.XML_REMOVE {
+			output = append(output, bits)
+		}
+
+		if bits == '\n' {
+			line, column = line+1, 1
+		} else {
+			column += 1
+		}
+	}
+
+	return output, malformed
+}
@@ -0,0 +1,29 @@
+package transform
+
+import (
+	"testing"
+	"utfcoder/types"
+)
+
+func TestCheckXML(t *testing.T) {
+	input := []uint32{'a', 0x09, 0x0B, '\n', 0xFFFE, 'b', 0x1F600, 0xFFFF}
+
+	output, malformed := CheckXML(input, types.XML_FLAG)
+	if len(output) != len(input) || len(malformed) != 3 {
+		t.Errorf(`CheckXML(%v, flag) = output=%v, malformed=%v, Expected = output=%v, malformed=%v`, input, output, len(malformed), input, 3)
+	}
+	if malformed[1].Offset != 4 || malformed[1].Line != 2 || malformed[1].Column != 1 {
+		t.Errorf(`CheckXML(%v, flag) = malformed=%v, Expected = offset=%v, line=%v, column=%v`, input, malformed[1], 4, 2, 1)
+	}
+
+	expected := []uint32{'a', 0x09, '\n', 'b', 0x1F600}
+	output, _ = CheckXML(input, types.XML_REMOVE)
+	if len(output) != len(expected) {
+		t.Fatalf(`CheckXML(%v, remove) = output=%v, Expected = output=%v`, input, output, expected)
+	}
+	for idx := range expected {
+		if output[idx] != expected[idx] {
+			t.Errorf(`CheckXML(%v, remove) = output=%v, Expected = output=%v`, input, output, expected)
+		}
+	}
+}
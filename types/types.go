@@ -16,3 +16,109 @@ const (
 	UTF_32LE string = "utf-32le"
 	UTF_32BE string = "utf-32be"
 )
+
+// source encoding of the commands which detect it from the input
+const AUTO string = "auto"
+
+// ascii-safe source literal flavors, usable as target encodings
+const (
+	ESCAPED_JAVA   string = "escaped-java"
+	ESCAPED_JSON   string = "escaped-json"
+	ESCAPED_C      string = "escaped-c"
+	ESCAPED_GO     string = "escaped-go"
+	ESCAPED_PYTHON string = "escaped-python"
+	ESCAPED_RUST   string = "escaped-rust"
+	ESCAPED_JS     string = "escaped-js"
+)
+
+// ErrorMode decides what the codecs do with bytes or code points they cannot represent
+type ErrorMode string
+
+const (
+	// replace substitutes every undecodable byte or unencodable code point with U+FFFD
+	REPLACE ErrorMode = "replace"
+	// surrogateescape maps every undecodable byte to a lone low surrogate in U+DC80–U+DCFF
+	// and writes it back as the original byte on encode, like python's error handler of the same name
+	SURROGATE_ESCAPE ErrorMode = "surrogateescape"
+)
+
+// EscapeScope decides which code points the escaped flavors write as escape sequences
+type EscapeScope string
+
+const (
+	// only code points beyond U+007F are escaped, like native2ascii
+	NON_ASCII EscapeScope = "nonascii"
+	// ascii control characters are escaped as well
+	NON_PRINTABLE EscapeScope = "nonprintable"
+)
+
+// escaped text source encodings besides the escaped-* flavors
+const (
+	// whitespace, comma or semicolon separated U+XXXX code points
+	CODEPOINTS string = "codepoints"
+	// %XX percent-encoded utf-8
+	PERCENT string = "percent"
+)
+
+// html and xml character reference encodings, usable as source and target encodings
+const (
+	HTML_ENTITIES string = "html-entities"
+	XML_CHARREF   string = "xml-charref"
+)
+
+// single byte charsets, the character reference encodings write ascii or latin-1 unescaped
+const (
+	ASCII        string = "ascii"
+	LATIN_1      string = "latin-1"
+	WINDOWS_1252 string = "windows-1252"
+)
+
+// multi byte charset of japanese windows, windows code page 932, usable as fallback charset
+const SHIFT_JIS string = "shift_jis"
+
+// Malformed describes an input sequence which could not be decoded
+type Malformed struct {
+	// byte offset of the sequence in the input
+	Offset int
+	// 1-based line and column (in code points) of the sequence
+	Line, Column int
+	Bytes        []byte
+	Reason       string
+}
+
+// XMLCheck decides what happens to code points XML 1.0 forbids
+type XMLCheck string
+
+const (
+	// forbidden code points are reported
+	XML_FLAG XMLCheck = "flag"
+	// forbidden code points are reported and dropped from the output
+	XML_REMOVE XMLCheck = "remove"
+)
+
+// NormalizationForm is one of the unicode normalization forms of UAX #15
+type NormalizationForm string
+
+const (
+	NFC  NormalizationForm = "nfc"
+	NFD  NormalizationForm = "nfd"
+	NFKC NormalizationForm = "nfkc"
+	NFKD NormalizationForm = "nfkd"
+)
+
+// LineEnding is a line end style, or the -eol target style
+type LineEnding string
+
+const (
+	EOL_LF   LineEnding = "lf"
+	EOL_CRLF LineEnding = "crlf"
+	EOL_CR   LineEnding = "cr"
+	// next line U+0085
+	EOL_NEL LineEnding = "nel"
+	// line separator U+2028
+	EOL_LS LineEnding = "ls"
+	// paragraph separator U+2029
+	EOL_PS LineEnding = "ps"
+	// line ends are left as they are
+	EOL_KEEP LineEnding = "keep"
+)
@@ -0,0 +1,96 @@
+package ucd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/runenames"
+)
+
+// the two letter general categories, in a fixed order
+var categories []string
+
+func init() {
+	for name := range unicode.Categories {
+		// LC (cased letter) is the union of Lu, Ll and Lt
+		if len(name) == 2 && name != "LC" {
+			categories = append(categories, name)
+		}
+	}
+	sort.Strings(categories)
+}
+
+// hangul syllables are named after their jamo (Unicode 3.12)
+const (
+	hangulBase   = 0xAC00
+	hangulCount  = 11172
+	vowelCount   = 21
+	trailerCount = 28
+)
+
+var leadingJamo = [19]string{"G", "GG", "N", "D", "DD", "R", "M", "B", "BB", "S", "SS", "", "J", "JJ", "C", "K", "T", "P", "H"}
+var vowelJamo = [vowelCount]string{"A", "AE", "YA", "YAE", "EO", "E", "YEO", "YE", "O", "WA", "WAE", "OE", "YO", "U", "WEO", "WE", "WI", "YU", "EU", "YI", "I"}
+var trailingJamo = [trailerCount]string{"", "G", "GG", "GS", "N", "NJ", "NH", "D", "L", "LG", "LM", "LB", "LS", "LT", "LP", "LH", "M", "B", "BS", "S", "SS", "NG", "J", "C", "K", "T", "P", "H"}
+
+// returns the unicode name of a code point. code points without a name of their own get a label in angle brackets,
+// like <control> or <unassigned>
+func Name(bits uint32) string {
+	if bits > unicode.MaxRune {
+		return "<invalid>"
+	}
+
+	if bits >= hangulBase && bits < hangulBase+hangulCount {
+		index := bits - hangulBase
+		return "HANGUL SYLLABLE " + leadingJamo[index/(vowelCount*trailerCount)] + vowelJamo[index%(vowelCount*trailerCount)/trailerCount] + trailingJamo[index%trailerCount]
+	}
+
+	name := runenames.Name(rune(bits))
+	switch {
+	case len(name) == 0:
+		if unicode.Is(unicode.Noncharacter_Code_Point, rune(bits)) {
+			return "<noncharacter>"
+		}
+		return "<unassigned>"
+	case strings.HasPrefix(name, "<CJK Ideograph"):
+		return fmt.Sprintf("CJK UNIFIED IDEOGRAPH-%04X", bits)
+	case strings.HasPrefix(name, "<Tangut Ideograph"):
+		return fmt.Sprintf("TANGUT IDEOGRAPH-%04X", bits)
+	case strings.HasSuffix(name, "Surrogate>"):
+		return "<surrogate>"
+	}
+	return name
+}
+
+// returns the two letter general category of a code point, Cn when it is unassigned
+func Category(bits uint32) string {
+	if bits <= unicode.MaxRune {
+		for _, name := range categories {
+			if unicode.Is(unicode.Categories[name], rune(bits)) {
+				return name
+			}
+		}
+	}
+	return "Cn"
+}
+
+// code point of every unicode name, built on the first Lookup
+var codepointsByName map[string]uint32
+var buildNamesOnce sync.Once
+
+// returns the code point with the unicode name, matched case insensitively. names in angle brackets aren't looked up
+func Lookup(name string) (uint32, bool) {
+	buildNamesOnce.Do(func() {
+		codepointsByName = make(map[string]uint32, 1<<16)
+		for bits := uint32(0); bits <= unicode.MaxRune; bits += 1 {
+			if name := Name(bits); !strings.HasPrefix(name, "<") {
+				codepointsByName[name] = bits
+			}
+		}
+	})
+
+	bits, ok := codepointsByName[strings.ToUpper(strings.TrimSpace(name))]
+	return bits, ok
+}
@@ -0,0 +1,64 @@
+package ucd
+
+import "testing"
+
+func TestName(t *testing.T) {
+	for _, test := range nameTestInputs {
+		if output := Name(test.bits); output != test.expected {
+			t.Errorf(`Name(%X) = output=%v, Expected = output=%v`, test.bits, output, test.expected)
+		}
+	}
+}
+
+func TestCategory(t *testing.T) {
+	for _, test := range categoryTestInputs {
+		if output := Category(test.bits); output != test.expected {
+			t.Errorf(`Category(%X) = output=%v, Expected = output=%v`, test.bits, output, test.expected)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, test := range nameTestInputs {
+		bits, ok := Lookup(test.expected)
+		// only the code points which have a name of their own can be looked up
+		if isLabel := test.expected[0] == '<'; ok == isLabel || (ok && bits != test.bits) {
+			t.Errorf(`Lookup(%v) = output=%X, ok=%v, Expected = output=%X, ok=%v`, test.expected, bits, ok, test.bits, !isLabel)
+		}
+	}
+
+	if bits, ok := Lookup("right single quotation mark"); !ok || bits != 0x2019 {
+		t.Errorf(`Lookup(right single quotation mark) = output=%X, ok=%v, Expected = output=2019, ok=true`, bits, ok)
+	}
+}
+
+var nameTestInputs = []struct {
+	bits     uint32
+	expected string
+}{
+	{'a', "LATIN SMALL LETTER A"},
+	{0xFEFF, "ZERO WIDTH NO-BREAK SPACE"},
+	{0x1F600, "GRINNING FACE"},
+	{0x4E00, "CJK UNIFIED IDEOGRAPH-4E00"},
+	{0xAC00, "HANGUL SYLLABLE GA"},
+	{0xD55C, "HANGUL SYLLABLE HAN"},
+	{0x0A, "<control>"},
+	{0xD800, "<surrogate>"},
+	{0xFFFF, "<noncharacter>"},
+	{0x0378, "<unassigned>"},
+}
+
+var categoryTestInputs = []struct {
+	bits     uint32
+	expected string
+}{
+	{'a', "Ll"},
+	{'A', "Lu"},
+	{'1', "Nd"},
+	{' ', "Zs"},
+	{0x0301, "Mn"},
+	{0xFEFF, "Cf"},
+	{0xD800, "Cs"},
+	{0xE000, "Co"},
+	{0x0378, "Cn"},
+}
@@ -6,16 +6,19 @@ import (
 	"utfcoder/utils"
 )
 
-func isSurrogateCodepoint(high byte, low byte) bool {
-	if (high >= 0xD8 && high <= 0xDF) || (low >= 0xD8 && low <= 0xDF) {
-		return true
-	}
-	return false
+func isHighSurrogate(bits uint32) bool {
+	return bits >= 0xD800 && bits <= 0xDBFF
+}
+
+func isLowSurrogate(bits uint32) bool {
+	return bits >= 0xDC00 && bits <= 0xDFFF
 }
 
 // returns Endianness string "le" or "be", has_BOM boolean
 func checkUTF16Endianness(bytes []byte) (types.Endianness, bool) {
-	if bytes[0] == 0xFF && bytes[1] == 0xFE {
+	if len(bytes) < 2 {
+		return types.BIG_ENDIAN, false
+	} else if bytes[0] == 0xFF && bytes[1] == 0xFE {
 		logger.Log("UTF-16 Little Endian format detected")
 		return types.LITTLE_ENDIAN, true
 	} else if bytes[0] == 0xFE && bytes[1] == 0xFF {
@@ -54,131 +57,169 @@ func extractBitsFromSurrogate(hSurrF, hSurrL, lSurrF, lSurrL byte) uint32 {
 	return bits
 }
 
-func ConvertToUTF8(input []byte, addBOM bool) ([]byte, error) {
-	logger.Log("\nConvert UTF-16", input, "To UTF-8")
+// returns the code point of the code unit (or surrogate pair) starting at input[i] and the number of bytes it
+// occupies. size 0 means a lone surrogate, or a code unit cut off by the end of input
+func DecodeAt(input []byte, i int, isBigEndian bool) (uint32, int) {
+	if i+1 >= len(input) {
+		return 0, 0
+	}
 
-	var output = make([]byte, 0, len(input))
+	unit := func(i int) uint32 {
+		if isBigEndian {
+			return extractBits(input[i], input[i+1])
+		}
+		return extractBits(input[i+1], input[i])
+	}
 
-	endianness, hasBOM := checkUTF16Endianness(input)
-	isSourceBigEndian := endianness == types.BIG_ENDIAN
+	bits := unit(i)
+	if isHighSurrogate(bits) && i+3 < len(input) && isLowSurrogate(unit(i+2)) {
+		if isBigEndian {
+			return extractBitsFromSurrogate(input[i], input[i+1], input[i+2], input[i+3]), 4
+		}
+		return extractBitsFromSurrogate(input[i+1], input[i], input[i+3], input[i+2]), 4
+	} else if isHighSurrogate(bits) || isLowSurrogate(bits) {
+		return 0, 0
+	}
+	return bits, 2
+}
 
-	startIdx := 0
-	if hasBOM {
-		startIdx = 2
+// returns the byte order Decode reads input of utf-16 in, from its byte order mark or its first code units
+func ByteOrder(input []byte) types.Endianness {
+	endianness, _ := checkUTF16Endianness(input)
+	return endianness
+}
+
+// returns the byte order of input and the index of its first code unit after the byte order mark
+func sourceByteOrder(input []byte, sourceEncoding string) (bool, int) {
+	switch sourceEncoding {
+	case types.UTF_16LE:
+		if len(input) > 1 && input[0] == 0xFF && input[1] == 0xFE {
+			return false, 2
+		}
+		return false, 0
+	case types.UTF_16BE:
+		if len(input) > 1 && input[0] == 0xFE && input[1] == 0xFF {
+			return true, 2
+		}
+		return true, 0
 	}
 
-	if addBOM {
-		// byte order mark for utf8 - 0xEFBBBF
-		output = append(output, 0xEF, 0xBB, 0xBF)
+	endianness, hasBOM := checkUTF16Endianness(input)
+	if hasBOM {
+		return endianness == types.BIG_ENDIAN, 2
 	}
+	return endianness == types.BIG_ENDIAN, 0
+}
+
+// returns the code points of input, the number of bytes carried as surrogate escapes and the malformed sequences.
+// sourceEncoding utf-16 detects the byte order, utf-16le and utf-16be force it
+func Decode(input []byte, sourceEncoding string, errorMode types.ErrorMode) ([]uint32, int, []types.Malformed) {
+	var codepoints = make([]uint32, 0, len(input)/2)
+	var escaped int
+	var malformed []types.Malformed
+	position := utils.NewPosition()
+
+	isSourceBigEndian, startIdx := sourceByteOrder(input, sourceEncoding)
 
-	for i := startIdx; i < len(input)-1; i += 2 {
-		var bits uint32
+	unit := func(i int) uint32 {
+		if isSourceBigEndian {
+			return extractBits(input[i], input[i+1])
+		}
+		return extractBits(input[i+1], input[i])
+	}
 
-		isSurrogate := isSurrogateCodepoint(input[i], input[i+1])
+	i := startIdx
+	for ; i+1 < len(input); i += 2 {
+		bits := unit(i)
 
-		if isSurrogate && i+3 < len(input) {
+		if isHighSurrogate(bits) && i+3 < len(input) && isLowSurrogate(unit(i+2)) {
 			if isSourceBigEndian {
 				bits = extractBitsFromSurrogate(input[i], input[i+1], input[i+2], input[i+3])
 			} else {
 				bits = extractBitsFromSurrogate(input[i+1], input[i], input[i+3], input[i+2])
 			}
 			i += 2
-		} else {
-			if isSourceBigEndian {
-				bits = extractBits(input[i], input[i+1])
+		} else if isHighSurrogate(bits) || isLowSurrogate(bits) {
+			malformed = append(malformed, position.Malformed(input, i, i+2, "lone surrogate"))
+
+			if errorMode == types.SURROGATE_ESCAPE && utils.IsEscapedByte(bits) {
+				// a lone surrogate in the escape range was written by the surrogateescape encoder, carry it through
+				escaped += 1
 			} else {
-				bits = extractBits(input[i+1], input[i])
+				bits = utils.GenerateUnknownCharacter(types.UTF_32)
 			}
 		}
 
-		if !utils.IsValidUnicodeRange(bits) {
-			bits = utils.GenerateUnknownCharacter(types.UTF_8)
-		} else if bits >= 0x10000 {
-			// Mark with prefix 1111 0xxx 10xx xxxx 10xx xxxx 10xx xxxx and fill the x's with the available bits
-			bits = (((bits & 0x1c0000) << 6) | ((bits & 0x30000) << 4)) | ((bits & 0xf000) << 4) | ((bits & 0xfc0) << 2) | (bits & 0x3f) | 0xf0808080
-		} else if bits >= 0x800 {
-			// Mark with prefix 1110 xxxx 10xx xxxx 10xx xxxx and fill the x's with the available bits
-			bits = ((bits & 0xf000) << 4) | ((bits & 0xfc0) << 2) | (bits & 0x3f) | 0xe08080
-		} else if bits >= 0x80 {
-			// Mark with prefix 110x xxxx 10xx xxxx and fill the x's with the available bits
-			bits = ((bits & 0x7c0) << 2) | (bits & 0x3f) | 0xc080
-		}
+		codepoints = append(codepoints, bits)
+		position.Advance(bits)
+	}
 
-		for range 4 {
-			b := byte(bits >> 24)
-			if b != 0 {
-				output = append(output, b)
-			}
-			bits = bits << 8
+	// a trailing odd byte can't form a code unit
+	if i < len(input) {
+		malformed = append(malformed, position.Malformed(input, i, len(input), "truncated code unit, odd length"))
+
+		if errorMode == types.SURROGATE_ESCAPE && input[i] >= 0x80 {
+			codepoints = append(codepoints, utils.EscapeByte(input[i]))
+			escaped += 1
+		} else {
+			codepoints = append(codepoints, utils.GenerateUnknownCharacter(types.UTF_32))
 		}
 	}
 
-	logger.Log("\nConverted to UTF-8", output)
-
-	return output, nil
+	return codepoints, escaped, malformed
 }
 
-func ConvertToUTF32(input []byte, targetEncoding string, addBOM bool) ([]byte, error) {
-	logger.Log("\nConvert UTF-16", input, "To UTF-32")
-
-	var output = make([]byte, 0, len(input))
+func Encode(codepoints []uint32, targetEncoding string, addBOM bool, errorMode types.ErrorMode) []byte {
+	var output = make([]byte, 0, len(codepoints)*2+2)
 
-	endianness, hasBOM := checkUTF16Endianness(input)
+	isTargetBigEndian := utils.IsBigEndian(targetEncoding)
 
-	isTargetBigEndian := targetEncoding == types.UTF_32BE || targetEncoding == types.UTF_32
-	isSourceBigEndian := endianness == types.BIG_ENDIAN
+	if addBOM {
+		output = utils.AppendBOM(output, targetEncoding)
+	}
 
-	startIdx := 0
-	if hasBOM {
-		startIdx = 2
+	for _, bits := range codepoints {
+		output = utils.AppendUTF16(output, bits, isTargetBigEndian, errorMode)
 	}
 
+	return output
+}
+
+func ConvertToUTF8(input []byte, addBOM bool) ([]byte, error) {
+	logger.Log("\nConvert UTF-16", input, "To UTF-8")
+
+	codepoints, _, _ := Decode(input, types.UTF_16, types.REPLACE)
+
+	var output = make([]byte, 0, len(input))
+
 	if addBOM {
-		if isTargetBigEndian {
-			// byte order mark for utf32 BE - 0x0000FEFF
-			output = append(output, 0, 0, 0xFE, 0xFF)
-		} else {
-			// byte order mark for utf16 LE - 0xFFFE0000
-			output = append(output, 0xFF, 0xFE, 0, 0)
-		}
+		output = utils.AppendBOM(output, types.UTF_8)
 	}
 
-	for i := startIdx; i < len(input)-1; i += 2 {
-		var bits uint32
+	for _, bits := range codepoints {
+		output = utils.AppendUTF8(output, bits, types.REPLACE)
+	}
 
-		isSurrogate := isSurrogateCodepoint(input[i], input[i+1])
+	logger.Log("\nConverted to UTF-8", output)
 
-		if isSurrogate && i+3 < len(input) {
-			if isSourceBigEndian {
-				bits = extractBitsFromSurrogate(input[i], input[i+1], input[i+2], input[i+3])
-			} else {
-				bits = extractBitsFromSurrogate(input[i+1], input[i], input[i+3], input[i+2])
-			}
-			i += 2
-		} else {
-			if isSourceBigEndian {
-				bits = extractBits(input[i], input[i+1])
-			} else {
-				bits = extractBits(input[i+1], input[i])
-			}
-		}
+	return output, nil
+}
 
-		if !utils.IsValidUnicodeRange(bits) {
-			bits = utils.GenerateUnknownCharacter(targetEncoding)
-		}
+func ConvertToUTF32(input []byte, targetEncoding string, addBOM bool) ([]byte, error) {
+	logger.Log("\nConvert UTF-16", input, "To UTF-32")
 
-		for range 4 {
-			if isTargetBigEndian {
-				b := byte(bits >> 24)
-				output = append(output, b)
-				bits = bits << 8
-			} else {
-				b := byte(bits)
-				output = append(output, b)
-				bits = bits >> 8
-			}
-		}
+	codepoints, _, _ := Decode(input, types.UTF_16, types.REPLACE)
+
+	var output = make([]byte, 0, len(codepoints)*4+4)
+
+	isTargetBigEndian := utils.IsBigEndian(targetEncoding)
+
+	if addBOM {
+		output = utils.AppendBOM(output, targetEncoding)
+	}
+
+	for _, bits := range codepoints {
+		output = utils.AppendUTF32(output, bits, isTargetBigEndian, types.REPLACE)
 	}
 
 	logger.Log("\nConverted to", targetEncoding, output)
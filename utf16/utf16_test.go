@@ -50,6 +50,55 @@ func TestConvertToUTF32BEWithBOM(t *testing.T) {
 	}
 }
 
+func TestDecodeMalformed(t *testing.T) {
+	// "a", a lone high surrogate, LF, a lone low surrogate and a trailing odd byte
+	input := []byte{'a', 0x00, 0x00, 0xD8, '\n', 0x00, 0x00, 0xDC, 'b'}
+	expected := []struct {
+		offset, line, column int
+		reason               string
+	}{
+		{2, 1, 2, "lone surrogate"},
+		{6, 2, 1, "lone surrogate"},
+		{8, 2, 2, "truncated code unit, odd length"},
+	}
+
+	codepoints, _, malformed := Decode(input, types.UTF_16LE, types.REPLACE)
+	if len(malformed) != len(expected) || len(codepoints) != 5 {
+		t.Fatalf(`Decode(%v) = codepoints=%v, malformed=%v, Expected = 5 code points and %v malformed sequences`, input, codepoints, malformed, len(expected))
+	}
+	for idx, m := range malformed {
+		if m.Offset != expected[idx].offset || m.Line != expected[idx].line || m.Column != expected[idx].column || m.Reason != expected[idx].reason {
+			t.Errorf(`Decode(%v) = malformed=%+v, Expected = malformed=%+v`, input, m, expected[idx])
+		}
+	}
+}
+
+func TestDecodeAt(t *testing.T) {
+	tests := []struct {
+		input       []byte
+		isBigEndian bool
+		bits        uint32
+		size        int
+	}{
+		{[]byte{'a', 0x00}, false, 'a', 2},
+		{[]byte{0x00, 'a'}, true, 'a', 2},
+		{[]byte{0x3D, 0xD8, 0x00, 0xDE}, false, 0x1F600, 4},
+		{[]byte{0xD8, 0x3D, 0xDE, 0x00}, true, 0x1F600, 4},
+		// a high surrogate without its low surrogate, a lone low surrogate and half a code unit
+		{[]byte{0x3D, 0xD8, 'a', 0x00}, false, 0, 0},
+		{[]byte{0x3D, 0xD8}, false, 0, 0},
+		{[]byte{0x00, 0xDE}, false, 0, 0},
+		{[]byte{'a'}, false, 0, 0},
+	}
+
+	for _, test := range tests {
+		bits, size := DecodeAt(test.input, 0, test.isBigEndian)
+		if bits != test.bits || size != test.size {
+			t.Errorf(`DecodeAt(%v, %v) = bits=%X, size=%v, Expected = bits=%X, size=%v`, test.input, test.isBigEndian, bits, size, test.bits, test.size)
+		}
+	}
+}
+
 func TestConvertToUTF8(t *testing.T) {
 	for idx := 0; idx < len(utf16LittleEndianTo8TestInputs); idx += 2 {
 		input := utf16LittleEndianTo8TestInputs[idx]
@@ -9,7 +9,9 @@ import (
 
 // returns Endianness string "le" or "be", has_BOM boolean
 func checkUTF32Endianness(bytes []byte) (types.Endianness, bool) {
-	if bytes[0] == 255 && bytes[1] == 254 && bytes[2] == 0 && bytes[3] == 0 {
+	if len(bytes) < 4 {
+		return types.LITTLE_ENDIAN, false
+	} else if bytes[0] == 255 && bytes[1] == 254 && bytes[2] == 0 && bytes[3] == 0 {
 		logger.Log("UTF-32 Little Endian format detected")
 		return types.LITTLE_ENDIAN, true
 	} else if bytes[0] == 0 && bytes[1] == 0 && bytes[2] == 254 && bytes[3] == 255 {
@@ -36,126 +38,167 @@ func isValidInput(input []byte) bool {
 	return len(input) != 0 && len(input)%4 == 0
 }
 
-func ConvertToUTF8(input []byte, addBOM bool) ([]byte, error) {
-	logger.Log("\nConvert UTF-32", input, "To UTF-8")
+// returns the byte order Decode reads input of utf-32 in, from its byte order mark or its first code units
+func ByteOrder(input []byte) types.Endianness {
+	endianness, _ := checkUTF32Endianness(input)
+	return endianness
+}
 
-	if !isValidInput(input) {
-		return []byte{}, errors.New("invalid input")
+// returns the byte order of input and the index of its first code unit after the byte order mark
+func sourceByteOrder(input []byte, sourceEncoding string) (bool, int) {
+	switch sourceEncoding {
+	case types.UTF_32LE:
+		if len(input) > 3 && input[0] == 0xFF && input[1] == 0xFE && input[2] == 0 && input[3] == 0 {
+			return false, 4
+		}
+		return false, 0
+	case types.UTF_32BE:
+		if len(input) > 3 && input[0] == 0 && input[1] == 0 && input[2] == 0xFE && input[3] == 0xFF {
+			return true, 4
+		}
+		return true, 0
 	}
 
 	endianness, hasBOM := checkUTF32Endianness(input)
+	if hasBOM {
+		return endianness == types.BIG_ENDIAN, 4
+	}
+	return endianness == types.BIG_ENDIAN, 0
+}
 
-	var output = make([]byte, 0, len(input))
+// returns the code point of the code unit starting at input[i] and the number of bytes it occupies. size 0 means a
+// surrogate, a value beyond U+10FFFF, or a code unit cut off by the end of input
+func DecodeAt(input []byte, i int, isBigEndian bool) (uint32, int) {
+	if i+3 >= len(input) {
+		return 0, 0
+	}
 
-	startIdx := 0
-	if hasBOM {
-		startIdx = 4
+	bits := uint32(input[i+3])<<24 | uint32(input[i+2])<<16 | uint32(input[i+1])<<8 | uint32(input[i])
+	if isBigEndian {
+		bits = uint32(input[i])<<24 | uint32(input[i+1])<<16 | uint32(input[i+2])<<8 | uint32(input[i+3])
 	}
 
-	if addBOM {
-		// byte order mark for utf8 - 0xEFBBBF
-		output = append(output, 0xEF, 0xBB, 0xBF)
+	if !utils.IsValidUnicodeRange(bits) {
+		return 0, 0
 	}
+	return bits, 4
+}
 
-	for i := startIdx; i+3 < len(input); i += 4 {
+// returns the code points of input, the number of bytes carried as surrogate escapes and the malformed sequences.
+// sourceEncoding utf-32 detects the byte order, utf-32le and utf-32be force it
+func Decode(input []byte, sourceEncoding string, errorMode types.ErrorMode) ([]uint32, int, []types.Malformed) {
+	var codepoints = make([]uint32, 0, len(input)/4)
+	var escaped int
+	var malformed []types.Malformed
+	position := utils.NewPosition()
+
+	isSourceBigEndian, startIdx := sourceByteOrder(input, sourceEncoding)
+
+	i := startIdx
+	for ; i+3 < len(input); i += 4 {
 		var bits uint32
 
-		if endianness == types.LITTLE_ENDIAN {
-			bits = uint32(input[i+3])<<24 | uint32(input[i+2])<<16 | uint32(input[i+1])<<8 | uint32(input[i])
-		} else {
+		if isSourceBigEndian {
 			bits = uint32(input[i])<<24 | uint32(input[i+1])<<16 | uint32(input[i+2])<<8 | uint32(input[i+3])
+		} else {
+			bits = uint32(input[i+3])<<24 | uint32(input[i+2])<<16 | uint32(input[i+1])<<8 | uint32(input[i])
 		}
 
 		if !utils.IsValidUnicodeRange(bits) {
-			bits = utils.GenerateUnknownCharacter(types.UTF_8)
-		} else if bits >= 0x10000 {
-			// Mark with prefix 1111 0xxx 10xx xxxx 10xx xxxx 10xx xxxx and fill the x's with the available bits
-			bits = (((bits & 0x1c0000) << 6) | ((bits & 0x30000) << 4)) | ((bits & 0xf000) << 4) | ((bits & 0xfc0) << 2) | (bits & 0x3f) | 0xf0808080
-		} else if bits >= 0x800 {
-			// Mark with prefix 1110 xxxx 10xx xxxx 10xx xxxx and fill the x's with the available bits
-			bits = ((bits & 0xf000) << 4) | ((bits & 0xfc0) << 2) | (bits & 0x3f) | 0xe08080
-		} else if bits >= 0x80 {
-			// Mark with prefix 110x xxxx 10xx xxxx and fill the x's with the available bits
-			bits = ((bits & 0x7c0) << 2) | (bits & 0x3f) | 0xc080
+			reason := "beyond U+10FFFF"
+			if bits >= 0xD800 && bits <= 0xDFFF {
+				reason = "lone surrogate"
+			}
+			malformed = append(malformed, position.Malformed(input, i, i+4, reason))
 		}
 
-		for bits != 0 {
-			b := byte(bits >> 24)
-			if b != 0 {
-				output = append(output, b)
-			}
-			bits = bits << 8
+		if errorMode == types.SURROGATE_ESCAPE && utils.IsEscapedByte(bits) {
+			// a lone surrogate in the escape range was written by the surrogateescape encoder, carry it through
+			escaped += 1
+		} else if !utils.IsValidUnicodeRange(bits) {
+			bits = utils.GenerateUnknownCharacter(types.UTF_32)
 		}
+
+		codepoints = append(codepoints, bits)
+		position.Advance(bits)
 	}
 
-	logger.Log("\nConverted to UTF-8", output)
+	// trailing bytes which can't form a code unit
+	if i < len(input) {
+		malformed = append(malformed, position.Malformed(input, i, len(input), "misaligned utf-32 length, not a multiple of 4"))
+	}
+	for ; i < len(input); i += 1 {
+		if errorMode == types.SURROGATE_ESCAPE && input[i] >= 0x80 {
+			codepoints = append(codepoints, utils.EscapeByte(input[i]))
+			escaped += 1
+		} else {
+			codepoints = append(codepoints, utils.GenerateUnknownCharacter(types.UTF_32))
+			break
+		}
+	}
 
-	return output, nil
+	return codepoints, escaped, malformed
 }
 
-func ConvertToUTF16(input []byte, targetEncoding string, addBOM bool) ([]byte, error) {
-	logger.Log("\nConvert UTF-32", input, "To UTF-16")
+func Encode(codepoints []uint32, targetEncoding string, addBOM bool, errorMode types.ErrorMode) []byte {
+	var output = make([]byte, 0, len(codepoints)*4+4)
+
+	isTargetBigEndian := utils.IsBigEndian(targetEncoding)
+
+	if addBOM {
+		output = utils.AppendBOM(output, targetEncoding)
+	}
+
+	for _, bits := range codepoints {
+		output = utils.AppendUTF32(output, bits, isTargetBigEndian, errorMode)
+	}
+
+	return output
+}
+
+func ConvertToUTF8(input []byte, addBOM bool) ([]byte, error) {
+	logger.Log("\nConvert UTF-32", input, "To UTF-8")
 
 	if !isValidInput(input) {
 		return []byte{}, errors.New("invalid input")
 	}
 
-	endianness, hasBOM := checkUTF32Endianness(input)
+	codepoints, _, _ := Decode(input, types.UTF_32, types.REPLACE)
 
 	var output = make([]byte, 0, len(input))
 
-	startIdx := 0
-	if hasBOM {
-		startIdx = 4
+	if addBOM {
+		output = utils.AppendBOM(output, types.UTF_8)
 	}
 
-	isTargetBigEndian := targetEncoding == types.UTF_16BE || targetEncoding == types.UTF_16
+	for _, bits := range codepoints {
+		output = utils.AppendUTF8(output, bits, types.REPLACE)
+	}
 
-	if addBOM {
-		if isTargetBigEndian {
-			// byte order mark for utf16 BE - 0xFEFF
-			output = append(output, 0xFE, 0xFF)
-		} else {
-			// byte order mark for utf16 LE - 0xFFFE
-			output = append(output, 0xFF, 0xFE)
-		}
+	logger.Log("\nConverted to UTF-8", output)
+
+	return output, nil
+}
+
+func ConvertToUTF16(input []byte, targetEncoding string, addBOM bool) ([]byte, error) {
+	logger.Log("\nConvert UTF-32", input, "To UTF-16")
+
+	if !isValidInput(input) {
+		return []byte{}, errors.New("invalid input")
 	}
 
-	for i := startIdx; i+3 < len(input); i += 4 {
-		var bits uint32
+	codepoints, _, _ := Decode(input, types.UTF_32, types.REPLACE)
 
-		if endianness == types.LITTLE_ENDIAN {
-			bits = uint32(input[i+3])<<24 | uint32(input[i+2])<<16 | uint32(input[i+1])<<8 | uint32(input[i])
-		} else {
-			bits = uint32(input[i])<<24 | uint32(input[i+1])<<16 | uint32(input[i+2])<<8 | uint32(input[i+3])
-		}
+	var output = make([]byte, 0, len(input))
 
-		var highSurrogate, lowSurrogate uint16
+	isTargetBigEndian := utils.IsBigEndian(targetEncoding)
 
-		if !utils.IsValidUnicodeRange(bits) {
-			bits = utils.GenerateUnknownCharacter(targetEncoding)
-		} else if bits >= 0x10000 {
-			bits = bits - 0x10000
-
-			// high surrogate - add 0xD800 with the leading 10 bits
-			highSurrogate = 0xD800 + uint16(bits>>10)
-			// low surrogate - add 0xDC00 with the trailing 10 bits
-			lowSurrogate = 0xDC00 + uint16(bits&0x03ff)
-		}
+	if addBOM {
+		output = utils.AppendBOM(output, targetEncoding)
+	}
 
-		if isTargetBigEndian {
-			if lowSurrogate != 0 {
-				output = append(output, byte(highSurrogate>>8), byte(highSurrogate), byte(lowSurrogate>>8), byte(lowSurrogate))
-			} else {
-				output = append(output, byte(bits>>8), byte(bits))
-			}
-		} else {
-			if lowSurrogate != 0 {
-				output = append(output, byte(highSurrogate), byte(highSurrogate>>8), byte(lowSurrogate), byte(lowSurrogate>>8))
-			} else {
-				output = append(output, byte(bits), byte(bits>>8))
-			}
-		}
+	for _, bits := range codepoints {
+		output = utils.AppendUTF16(output, bits, isTargetBigEndian, types.REPLACE)
 	}
 
 	logger.Log("\nConverted to", targetEncoding, output)
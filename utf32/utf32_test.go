@@ -30,6 +30,22 @@ func TestInvalidInputConvertToUTF8(t *testing.T) {
 	}
 }
 
+func TestDecodeMalformed(t *testing.T) {
+	// a lone surrogate, a value beyond U+10FFFF and two trailing bytes
+	input := []byte{0x00, 0xD8, 0x00, 0x00, 0x00, 0x00, 0x11, 0x00, 'a', 0x00}
+	expected := []string{"lone surrogate", "beyond U+10FFFF", "misaligned utf-32 length, not a multiple of 4"}
+
+	_, _, malformed := Decode(input, types.UTF_32LE, types.REPLACE)
+	if len(malformed) != len(expected) {
+		t.Fatalf(`Decode(%v) = malformed=%v, Expected = %v malformed sequences`, input, malformed, len(expected))
+	}
+	for idx, m := range malformed {
+		if m.Offset != idx*4 || m.Column != idx+1 || m.Reason != expected[idx] {
+			t.Errorf(`Decode(%v) = malformed=%+v, Expected = offset=%v, column=%v, reason=%v`, input, m, idx*4, idx+1, expected[idx])
+		}
+	}
+}
+
 func TestConvertToUTF16(t *testing.T) {
 	for idx := 0; idx < len(utf32To16LittleEndianTestInputs); idx += 2 {
 		input := utf32To16LittleEndianTestInputs[idx]
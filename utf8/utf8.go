@@ -6,60 +6,178 @@ import (
 	"utfcoder/utils"
 )
 
-func ConvertToUTF32(input []byte, targetEncoding string, addBOM bool) ([]byte, error) {
-	logger.Log("\nConvert UTF-8", input, "To UTF-32")
+// returns the code point starting at input[i] and the number of bytes it occupies. size 0 means input[i] can't be decoded
+func decodeSequence(input []byte, i int) (uint32, int) {
+	var bits, minBits uint32
+	var size int
+
+	if input[i]&0x80 == 0 {
+		return uint32(input[i]), 1
+	} else if input[i]&0xf8 == 240 {
+		bits, size, minBits = uint32(input[i]&0x07), 4, 0x10000
+	} else if input[i]&0xf0 == 224 {
+		bits, size, minBits = uint32(input[i]&0x0f), 3, 0x800
+	} else if input[i]&0xe0 == 192 {
+		bits, size, minBits = uint32(input[i]&0x1f), 2, 0x80
+	} else {
+		return 0, 0
+	}
 
-	var output = make([]byte, 0, len(input))
+	if i+size > len(input) {
+		return 0, 0
+	}
 
-	isTargetBigEndian := targetEncoding == types.UTF_32BE || targetEncoding == types.UTF_32
+	for j := 1; j < size; j += 1 {
+		// every trailing byte must be marked with the prefix 10xx xxxx
+		if input[i+j]&0xc0 != 128 {
+			return 0, 0
+		}
+		bits = bits<<6 | uint32(input[i+j]&0x3f)
+	}
 
-	startIdx := 0
-	// check if the first 3 bytes represent byte order mark for utf-8 i.e. 0xEFBBBF
-	if len(input) > 2 && input[0] == 0xEF && input[1] == 0xBB && input[2] == 0xBF {
-		startIdx = 3
+	// reject overlong forms, utf-16 surrogates and anything beyond U+10FFFF
+	if bits < minBits || !utils.IsValidUnicodeRange(bits) {
+		return 0, 0
 	}
 
-	if addBOM {
-		if isTargetBigEndian {
-			// byte order mark for utf32 BE - 0x0000FEFF
-			output = append(output, 0, 0, 0xFE, 0xFF)
-		} else {
-			// byte order mark for utf16 LE - 0xFFFE0000
-			output = append(output, 0xFF, 0xFE, 0, 0)
+	return bits, size
+}
+
+// returns the code point starting at input[i] and the number of bytes it occupies, like decodeSequence
+func DecodeAt(input []byte, i int) (uint32, int) {
+	return decodeSequence(input, i)
+}
+
+// returns the length of the malformed sequence starting at input[i], which decodeSequence rejected, and why it is malformed
+func diagnose(input []byte, i int) (int, string) {
+	lead := input[i]
+
+	isContinuation := func(j int) bool {
+		return j < len(input) && input[j]&0xc0 == 0x80
+	}
+
+	switch {
+	case lead >= 0x80 && lead <= 0xBF:
+		return 1, "unexpected continuation byte"
+	case lead == 0xC0 || lead == 0xC1:
+		if isContinuation(i + 1) {
+			return 2, "overlong encoding"
 		}
+		return 1, "overlong encoding"
+	case lead >= 0xF5 && lead <= 0xF7:
+		return 1, "beyond U+10FFFF"
+	case lead >= 0xF8:
+		return 1, "invalid byte"
 	}
 
-	for i := startIdx; i < len(input); i += 1 {
-		var bits uint32
-
-		if input[i]&0xf8 == 240 && i+3 < len(input) {
-			bits = uint32(input[i]&0x07)<<18 | uint32(input[i+1]&0x3f)<<12 | uint32(input[i+2]&0x3f)<<6 | uint32(input[i+3]&0x3f)
-			i += 3
-		} else if input[i]&0xf0 == 224 && i+2 < len(input) {
-			bits = uint32(input[i]&0x0f)<<12 | uint32(input[i+1]&0x3f)<<6 | uint32(input[i+2]&0x3f)
-			i += 2
-		} else if input[i]&0xe0 == 192 && i+1 < len(input) {
-			bits = uint32(input[i]&0x1f)<<6 | uint32(input[i+1]&0x3f)
-			i += 1
-		} else {
-			bits = uint32(input[i])
+	size := 4
+	if lead < 0xE0 {
+		size = 2
+	} else if lead < 0xF0 {
+		size = 3
+	}
+
+	// the second byte tells overlong forms, surrogates and values beyond U+10FFFF apart
+	if isContinuation(i + 1) {
+		second := input[i+1]
+		reason := ""
+		if (lead == 0xE0 && second < 0xA0) || (lead == 0xF0 && second < 0x90) {
+			reason = "overlong encoding"
+		} else if lead == 0xED && second >= 0xA0 {
+			reason = "lone surrogate"
+		} else if lead == 0xF4 && second >= 0x90 {
+			reason = "beyond U+10FFFF"
 		}
 
-		if !utils.IsValidUnicodeRange(bits) {
-			bits = utils.GenerateUnknownCharacter(targetEncoding)
+		if len(reason) != 0 {
+			length := 2
+			for length < size && isContinuation(i+length) {
+				length += 1
+			}
+			return length, reason
 		}
+	}
+
+	length := 1
+	for length < size && isContinuation(i+length) {
+		length += 1
+	}
+	return length, "truncated sequence"
+}
+
+// returns the code points of input, the number of bytes carried as surrogate escapes and the malformed sequences.
+// every byte of a malformed sequence is replaced (or escaped) on its own
+func Decode(input []byte, errorMode types.ErrorMode) ([]uint32, int, []types.Malformed) {
+	var codepoints = make([]uint32, 0, len(input))
+	var escaped int
+	var malformed []types.Malformed
+
+	startIdx := 0
+	// check if the first 3 bytes represent byte order mark for utf-8 i.e. 0xEFBBBF
+	if len(input) > 2 && input[0] == 0xEF && input[1] == 0xBB && input[2] == 0xBF {
+		startIdx = 3
+	}
+
+	position := utils.NewPosition()
+	// end of the last malformed sequence reported, its bytes aren't reported again
+	reportedIdx := startIdx
 
-		for range 4 {
-			if isTargetBigEndian {
-				b := byte(bits >> 24)
-				output = append(output, b)
-				bits = bits << 8
+	for i := startIdx; i < len(input); {
+		bits, size := decodeSequence(input, i)
+
+		if size == 0 {
+			if i >= reportedIdx {
+				length, reason := diagnose(input, i)
+				malformed = append(malformed, position.Malformed(input, i, i+length, reason))
+				reportedIdx = i + length
+			}
+
+			if errorMode == types.SURROGATE_ESCAPE {
+				bits = utils.EscapeByte(input[i])
+				escaped += 1
 			} else {
-				b := byte(bits)
-				output = append(output, b)
-				bits = bits >> 8
+				bits = utils.GenerateUnknownCharacter(types.UTF_32)
 			}
+			size = 1
 		}
+
+		codepoints = append(codepoints, bits)
+		position.Advance(bits)
+		i += size
+	}
+
+	return codepoints, escaped, malformed
+}
+
+func Encode(codepoints []uint32, addBOM bool, errorMode types.ErrorMode) []byte {
+	var output = make([]byte, 0, len(codepoints))
+
+	if addBOM {
+		output = utils.AppendBOM(output, types.UTF_8)
+	}
+
+	for _, bits := range codepoints {
+		output = utils.AppendUTF8(output, bits, errorMode)
+	}
+
+	return output
+}
+
+func ConvertToUTF32(input []byte, targetEncoding string, addBOM bool) ([]byte, error) {
+	logger.Log("\nConvert UTF-8", input, "To UTF-32")
+
+	codepoints, _, _ := Decode(input, types.REPLACE)
+
+	var output = make([]byte, 0, len(codepoints)*4+4)
+
+	isTargetBigEndian := utils.IsBigEndian(targetEncoding)
+
+	if addBOM {
+		output = utils.AppendBOM(output, targetEncoding)
+	}
+
+	for _, bits := range codepoints {
+		output = utils.AppendUTF32(output, bits, isTargetBigEndian, types.REPLACE)
 	}
 
 	logger.Log("\nConverted to", targetEncoding, output)
@@ -70,67 +188,18 @@ func ConvertToUTF32(input []byte, targetEncoding string, addBOM bool) ([]byte, e
 func ConvertToUTF16(input []byte, targetEncoding string, addBOM bool) ([]byte, error) {
 	logger.Log("\nConvert UTF-8", input, "To", targetEncoding)
 
-	var output = make([]byte, 0, len(input))
+	codepoints, _, _ := Decode(input, types.REPLACE)
 
-	isTargetBigEndian := targetEncoding == types.UTF_16BE || targetEncoding == types.UTF_16
+	var output = make([]byte, 0, len(codepoints)*2+2)
 
-	startIdx := 0
-	// check if the first 3 bytes represent byte order mark for utf-8 i.e. 0xEFBBBF
-	if len(input) > 2 && input[0] == 0xEF && input[1] == 0xBB && input[2] == 0xBF {
-		startIdx = 3
-	}
+	isTargetBigEndian := utils.IsBigEndian(targetEncoding)
 
 	if addBOM {
-		if isTargetBigEndian {
-			// byte order mark for utf16 BE - 0xFEFF
-			output = append(output, 0xFE, 0xFF)
-		} else {
-			// byte order mark for utf16 LE - 0xFFFE
-			output = append(output, 0xFF, 0xFE)
-		}
+		output = utils.AppendBOM(output, targetEncoding)
 	}
 
-	for i := startIdx; i < len(input); i += 1 {
-		var bits uint32
-		var highSurrogate, lowSurrogate uint16
-
-		if input[i]&0xf8 == 240 && i+3 < len(input) {
-			bits = uint32(input[i]&0x07)<<18 | uint32(input[i+1]&0x3f)<<12 | uint32(input[i+2]&0x3f)<<6 | uint32(input[i+3]&0x3f)
-			i += 3
-		} else if input[i]&0xf0 == 224 && i+2 < len(input) {
-			bits = uint32(input[i]&0x0f)<<12 | uint32(input[i+1]&0x3f)<<6 | uint32(input[i+2]&0x3f)
-			i += 2
-		} else if input[i]&0xe0 == 192 && i+1 < len(input) {
-			bits = uint32(input[i]&0x1f)<<6 | uint32(input[i+1]&0x3f)
-			i += 1
-		} else {
-			bits = uint32(input[i])
-		}
-
-		if !utils.IsValidUnicodeRange(bits) {
-			bits = utils.GenerateUnknownCharacter(targetEncoding)
-		} else if bits >= 0x10000 {
-			bits = bits - 0x10000
-
-			// high surrogate - add 0xD800 with the leading 10 bits
-			highSurrogate = 0xD800 + uint16(bits>>10)
-			// low surrogate - add 0xDC00 with the trailing 10 bits
-			lowSurrogate = 0xDC00 + uint16(bits&0x03ff)
-		}
-
-		if isTargetBigEndian {
-			if lowSurrogate != 0 {
-				output = append(output, byte(highSurrogate>>8), byte(highSurrogate), byte(lowSurrogate>>8), byte(lowSurrogate))
-			} else {
-				output = append(output, byte(bits>>8), byte(bits))
-			}
-		} else {
-			if lowSurrogate != 0 {
-				output = append(output, byte(highSurrogate), byte(highSurrogate>>8), byte(lowSurrogate), byte(lowSurrogate>>8))
-			} else {
-				output = append(output, byte(bits), byte(bits>>8))
-			}
-		}
+	for _, bits := range codepoints {
+		output = utils.AppendUTF16(output, bits, isTargetBigEndian, types.REPLACE)
 	}
 
 	logger.Log("\nConverted to", targetEncoding, output)
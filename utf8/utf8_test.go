@@ -287,3 +287,86 @@ var utf8To16BigEndianTestInputs = [][]byte{
 
 	{237, 156, 128}, {0xD7, 0x00}, // U+D700 valid BMP
 }
+
+func TestDecodeSurrogateEscape(t *testing.T) {
+	for idx := 0; idx < len(utf8SurrogateEscapeTestInputs); idx += 2 {
+		input := utf8SurrogateEscapeTestInputs[idx]
+		expected := utf8SurrogateEscapeTestInputs[idx+1]
+		codepoints, _, _ := Decode(input, types.SURROGATE_ESCAPE)
+		output := Encode(codepoints, false, types.SURROGATE_ESCAPE)
+
+		if !bytes.Equal(input, output) {
+			t.Errorf(`Encode(Decode(%v)) = output=%v, Expected = output=%v`, input, output, input)
+		}
+
+		var escaped []byte
+		for _, bits := range codepoints {
+			if bits >= 0xDC80 && bits <= 0xDCFF {
+				escaped = append(escaped, byte(bits))
+			}
+		}
+		if !bytes.Equal(expected, escaped) {
+			t.Errorf(`Decode(%v) = escaped=%v, Expected = escaped=%v`, input, escaped, expected)
+		}
+	}
+}
+
+func TestDecodeReplace(t *testing.T) {
+	input := []byte{0x43, 0x61, 0x66, 0xE9, 0xC0, 0x80}
+	expected := []uint32{0x43, 0x61, 0x66, 0xFFFD, 0xFFFD, 0xFFFD}
+	codepoints, escaped, _ := Decode(input, types.REPLACE)
+
+	if len(codepoints) != len(expected) || escaped != 0 {
+		t.Fatalf(`Decode(%v) = codepoints=%v, escaped=%v, Expected = codepoints=%v, escaped=%v`, input, codepoints, escaped, expected, 0)
+	}
+	for idx := range expected {
+		if codepoints[idx] != expected[idx] {
+			t.Errorf(`Decode(%v) = codepoints=%v, Expected = codepoints=%v`, input, codepoints, expected)
+		}
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	for _, test := range utf8MalformedTestInputs {
+		_, _, malformed := Decode(test.input, types.REPLACE)
+
+		if len(malformed) != 1 {
+			t.Errorf(`Decode(%v) = malformed=%v, Expected = one malformed sequence`, test.input, malformed)
+			continue
+		}
+		if m := malformed[0]; m.Offset != test.offset || m.Column != test.column || m.Line != test.line || !bytes.Equal(m.Bytes, test.bytes) || m.Reason != test.reason {
+			t.Errorf(`Decode(%v) = malformed=%+v, Expected = offset=%v, line=%v, column=%v, bytes=%v, reason=%v`, test.input, m, test.offset, test.line, test.column, test.bytes, test.reason)
+		}
+	}
+}
+
+var utf8MalformedTestInputs = []struct {
+	input        []byte
+	offset       int
+	line, column int
+	bytes        []byte
+	reason       string
+}{
+	{[]byte{'a', 0x80}, 1, 1, 2, []byte{0x80}, "unexpected continuation byte"},
+	{[]byte{0xC0, 0xAF}, 0, 1, 1, []byte{0xC0, 0xAF}, "overlong encoding"},
+	{[]byte{0xE0, 0x80, 0xAF}, 0, 1, 1, []byte{0xE0, 0x80, 0xAF}, "overlong encoding"},
+	{[]byte{0xF0, 0x8F, 0xBF, 0xBF}, 0, 1, 1, []byte{0xF0, 0x8F, 0xBF, 0xBF}, "overlong encoding"},
+	{[]byte{'a', '\n', 0xC3, 0xA9, 0xE2, 0x82}, 4, 2, 2, []byte{0xE2, 0x82}, "truncated sequence"},
+	{[]byte{0xE2, 0x82, 'a'}, 0, 1, 1, []byte{0xE2, 0x82}, "truncated sequence"},
+	{[]byte{0xED, 0xA0, 0x80}, 0, 1, 1, []byte{0xED, 0xA0, 0x80}, "lone surrogate"},
+	{[]byte{0xF4, 0x90, 0x80, 0x80}, 0, 1, 1, []byte{0xF4, 0x90, 0x80, 0x80}, "beyond U+10FFFF"},
+	{[]byte{0xF5, 'a'}, 0, 1, 1, []byte{0xF5}, "beyond U+10FFFF"},
+	{[]byte{0xFF}, 0, 1, 1, []byte{0xFF}, "invalid byte"},
+}
+
+// utf-8 inputs with undecodable bytes and the bytes expected to be escaped
+var utf8SurrogateEscapeTestInputs = [][]byte{
+	{0x43, 0x61, 0x66, 0xE9}, {0xE9}, // latin-1 é
+	{0xC3, 0xA9, 0xE9}, {0xE9}, // valid é followed by latin-1 é
+	{0xC0, 0x80}, {0xC0, 0x80}, // overlong NUL
+	{0xED, 0xA0, 0x80}, {0xED, 0xA0, 0x80}, // utf-8 encoded surrogate U+D800
+	{0xF4, 0x90, 0x80, 0x80}, {0xF4, 0x90, 0x80, 0x80}, // beyond U+10FFFF
+	{0xE2, 0x82}, {0xE2, 0x82}, // truncated €
+	{0xFF, 0x41, 0xFE}, {0xFF, 0xFE}, // invalid lead bytes around 'A'
+	{0xF0, 0x9F, 0x98, 0x80}, {}, // 😀 (U+1F600)
+}
@@ -0,0 +1,118 @@
+package utils
+
+import "utfcoder/types"
+
+func IsBigEndian(encoding string) bool {
+	// utf-16 and utf-32 without an explicit byte order are written as big endian
+	return encoding == types.UTF_16 || encoding == types.UTF_16BE || encoding == types.UTF_32 || encoding == types.UTF_32BE
+}
+
+// surrogateescape - undecodable byte 0x80-0xFF is carried as the lone low surrogate U+DC80-U+DCFF
+func EscapeByte(b byte) uint32 {
+	return 0xDC00 | uint32(b)
+}
+
+func IsEscapedByte(bits uint32) bool {
+	return bits >= 0xDC80 && bits <= 0xDCFF
+}
+
+// returns the high and low surrogate for a code point beyond the basic multilingual plane
+func SplitSurrogates(bits uint32) (uint16, uint16) {
+	bits = bits - 0x10000
+
+	// high surrogate - add 0xD800 with the leading 10 bits
+	highSurrogate := 0xD800 + uint16(bits>>10)
+	// low surrogate - add 0xDC00 with the trailing 10 bits
+	lowSurrogate := 0xDC00 + uint16(bits&0x03ff)
+
+	return highSurrogate, lowSurrogate
+}
+
+func AppendBOM(output []byte, encoding string) []byte {
+	switch encoding {
+	case types.UTF_8:
+		// byte order mark for utf8 - 0xEFBBBF
+		return append(output, 0xEF, 0xBB, 0xBF)
+	case types.UTF_16, types.UTF_16BE:
+		// byte order mark for utf16 BE - 0xFEFF
+		return append(output, 0xFE, 0xFF)
+	case types.UTF_16LE:
+		// byte order mark for utf16 LE - 0xFFFE
+		return append(output, 0xFF, 0xFE)
+	case types.UTF_32, types.UTF_32BE:
+		// byte order mark for utf32 BE - 0x0000FEFF
+		return append(output, 0, 0, 0xFE, 0xFF)
+	case types.UTF_32LE:
+		// byte order mark for utf32 LE - 0xFFFE0000
+		return append(output, 0xFF, 0xFE, 0, 0)
+	}
+	return output
+}
+
+func AppendUTF8(output []byte, bits uint32, errorMode types.ErrorMode) []byte {
+	if errorMode == types.SURROGATE_ESCAPE && IsEscapedByte(bits) {
+		return append(output, byte(bits))
+	}
+
+	if !IsValidUnicodeRange(bits) {
+		bits = GenerateUnknownCharacter(types.UTF_8)
+	} else if bits >= 0x10000 {
+		// Mark with prefix 1111 0xxx 10xx xxxx 10xx xxxx 10xx xxxx and fill the x's with the available bits
+		bits = (((bits & 0x1c0000) << 6) | ((bits & 0x30000) << 4)) | ((bits & 0xf000) << 4) | ((bits & 0xfc0) << 2) | (bits & 0x3f) | 0xf0808080
+	} else if bits >= 0x800 {
+		// Mark with prefix 1110 xxxx 10xx xxxx 10xx xxxx and fill the x's with the available bits
+		bits = ((bits & 0xf000) << 4) | ((bits & 0xfc0) << 2) | (bits & 0x3f) | 0xe08080
+	} else if bits >= 0x80 {
+		// Mark with prefix 110x xxxx 10xx xxxx and fill the x's with the available bits
+		bits = ((bits & 0x7c0) << 2) | (bits & 0x3f) | 0xc080
+	} else if bits == 0 {
+		return append(output, 0)
+	}
+
+	for bits != 0 {
+		b := byte(bits >> 24)
+		if b != 0 {
+			output = append(output, b)
+		}
+		bits = bits << 8
+	}
+
+	return output
+}
+
+func AppendUTF16(output []byte, bits uint32, isBigEndian bool, errorMode types.ErrorMode) []byte {
+	var highSurrogate, lowSurrogate uint16
+
+	if errorMode == types.SURROGATE_ESCAPE && IsEscapedByte(bits) {
+		// the escape is kept as a lone surrogate code unit so that it survives the trip back to utf-8
+	} else if !IsValidUnicodeRange(bits) {
+		bits = GenerateUnknownCharacter(types.UTF_16)
+	} else if bits >= 0x10000 {
+		highSurrogate, lowSurrogate = SplitSurrogates(bits)
+	}
+
+	if isBigEndian {
+		if lowSurrogate != 0 {
+			return append(output, byte(highSurrogate>>8), byte(highSurrogate), byte(lowSurrogate>>8), byte(lowSurrogate))
+		}
+		return append(output, byte(bits>>8), byte(bits))
+	}
+
+	if lowSurrogate != 0 {
+		return append(output, byte(highSurrogate), byte(highSurrogate>>8), byte(lowSurrogate), byte(lowSurrogate>>8))
+	}
+	return append(output, byte(bits), byte(bits>>8))
+}
+
+func AppendUTF32(output []byte, bits uint32, isBigEndian bool, errorMode types.ErrorMode) []byte {
+	if errorMode == types.SURROGATE_ESCAPE && IsEscapedByte(bits) {
+		// the escape is kept as is so that it survives the trip back to utf-8
+	} else if !IsValidUnicodeRange(bits) {
+		bits = GenerateUnknownCharacter(types.UTF_32)
+	}
+
+	if isBigEndian {
+		return append(output, byte(bits>>24), byte(bits>>16), byte(bits>>8), byte(bits))
+	}
+	return append(output, byte(bits), byte(bits>>8), byte(bits>>16), byte(bits>>24))
+}
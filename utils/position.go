@@ -0,0 +1,26 @@
+package utils
+
+import "utfcoder/types"
+
+// Position is the 1-based line and column, counted in code points, a decoder is at
+type Position struct {
+	Line, Column int
+}
+
+func NewPosition() Position {
+	return Position{Line: 1, Column: 1}
+}
+
+// moves the position past a decoded code point
+func (p *Position) Advance(bits uint32) {
+	if bits == '\n' {
+		p.Line, p.Column = p.Line+1, 1
+	} else {
+		p.Column += 1
+	}
+}
+
+// returns the report of the malformed input[offset:end] at the position
+func (p Position) Malformed(input []byte, offset int, end int, reason string) types.Malformed {
+	return types.Malformed{Offset: offset, Line: p.Line, Column: p.Column, Bytes: input[offset:end], Reason: reason}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"utfcoder/codec"
+	"utfcoder/types"
+)
+
+func runValidate(c *cli, args []string) int {
+	var sourceFile, fromEncoding string
+
+	fs := c.newFlagSet("validate")
+	sourceFlags(fs, &sourceFile, &fromEncoding)
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	sourceFile, err := sourceArgument(fs, sourceFile)
+	if err != nil {
+		return c.fail(exitUsage, err)
+	}
+
+	data, err := c.readSource(sourceFile)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	// escaping keeps a utf-32 length which isn't a multiple of 4 from failing the whole decode
+	_, report, encoding, err := decodeSource(data, fromEncoding, codec.Options{ErrorMode: types.SURROGATE_ESCAPE})
+	if err != nil {
+		return c.fail(exitUsage, err)
+	}
+
+	if len(report.Malformed) == 0 {
+		fmt.Fprintf(c.stdout, "%v: valid %v\n", sourceFile, encoding)
+		return exitOK
+	}
+
+	fmt.Fprintf(c.stdout, "%v: invalid %v, %v malformed sequences\n", sourceFile, encoding, len(report.Malformed))
+	for _, malformed := range report.Malformed {
+		fmt.Fprintf(c.stdout, "  offset %v (line %v, column %v): %v [% X]\n", malformed.Offset, malformed.Line, malformed.Column, malformed.Reason, malformed.Bytes)
+	}
+	return exitInvalid
+}
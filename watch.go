@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+	"utfcoder/logger"
+	"utfcoder/types"
+)
+
+// notifier tells when a watched directory may have changed, so it is scanned right away instead of at the next tick
+type notifier interface {
+	Events() <-chan struct{}
+	Close() error
+}
+
+// watchedFile is the size and modification time a file had when it was last seen
+type watchedFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// pendingFile is a file which is waited for to stop changing
+type pendingFile struct {
+	watchedFile
+	since time.Time
+}
+
+// watch converts the files appearing in a directory with batch, one at a time
+type watch struct {
+	b         batch
+	dir       string
+	statePath string
+	interval  time.Duration
+	stable    time.Duration
+	isPolling bool
+	isOnce    bool
+	// the files converted, by name, as the state file keeps them across restarts
+	state   map[string]watchedFile
+	pending map[string]pendingFile
+}
+
+func (w *watch) loadState() error {
+	w.state = map[string]watchedFile{}
+
+	data, err := os.ReadFile(w.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &w.state); err != nil {
+		return fmt.Errorf("invalid state file %v: %w", w.statePath, err)
+	}
+	return nil
+}
+
+func (w *watch) saveState() error {
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(w.statePath, append(data, '\n'), "")
+}
+
+// returns the names of the files which stayed the same for w.stable, and whether others are still changing
+func (w *watch) scan(now time.Time) ([]string, bool, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	statePath, _ := filepath.Abs(w.statePath)
+
+	var ready []string
+	isChanging := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || w.b.exclude.match(name) || (len(w.b.include) != 0 && !w.b.include.match(name)) {
+			continue
+		}
+
+		path := filepath.Join(w.dir, name)
+		if absolutePath, _ := filepath.Abs(path); absolutePath == statePath {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// deleted since the directory was read
+			continue
+		}
+
+		seen := watchedFile{Size: info.Size(), ModTime: info.ModTime()}
+		if converted, ok := w.state[name]; ok && converted.Size == seen.Size && converted.ModTime.Equal(seen.ModTime) {
+			delete(w.pending, name)
+			continue
+		}
+
+		pending, ok := w.pending[name]
+		if !ok || pending.Size != seen.Size || !pending.ModTime.Equal(seen.ModTime) {
+			pending = pendingFile{watchedFile: seen, since: now}
+			w.pending[name] = pending
+		}
+
+		if now.Sub(pending.since) >= w.stable {
+			ready = append(ready, name)
+		} else {
+			isChanging = true
+		}
+	}
+
+	return ready, isChanging, nil
+}
+
+// converts the ready files and records them in the state file, returns whether any failed
+func (w *watch) convert(c *cli, ready []string) (bool, error) {
+	hasFailed := false
+
+	for _, name := range ready {
+		result := w.b.convert(batchJob{path: filepath.Join(w.dir, name), relativePath: name})
+		fmt.Fprintf(c.stdout, "%v: %v (%v)\n", name, result.status, result.detail)
+		hasFailed = hasFailed || result.status == batchFailed
+
+		// a file which failed is tried again once it changes, not at every scan
+		w.state[name] = w.pending[name].watchedFile
+		delete(w.pending, name)
+		if err := w.saveState(); err != nil {
+			return hasFailed, err
+		}
+	}
+
+	return hasFailed, nil
+}
+
+func runWatch(c *cli, args []string) int {
+	w := watch{b: batch{cfg: config{fromEncoding: types.AUTO, isBatch: true}}, pending: map[string]pendingFile{}}
+
+	fs := c.newFlagSet("watch")
+	w.b.cfg.registerConversion(fs)
+	fs.StringVar(&w.b.outputDir, "o", "", "output `directory` the converted files are written to")
+	fs.Var(&w.b.include, "include", "only converts files whose name matches `glob`, may be repeated")
+	fs.Var(&w.b.exclude, "exclude", "skips files whose name matches `glob`, may be repeated")
+	fs.StringVar(&w.statePath, "state", "", "state `file` recording the files converted, so a restart doesn't convert them again. .utfcoder-watch.json in the output directory by default")
+	fs.DurationVar(&w.interval, "interval", time.Second, "how often the directory is scanned")
+	fs.DurationVar(&w.stable, "stable", 2*time.Second, "how long a file has to keep its size and modification time before it is converted")
+	fs.BoolVar(&w.isPolling, "poll", false, "scans the directory every -interval instead of waiting for inotify events")
+	fs.BoolVar(&w.isOnce, "once", false, "exits once the files there are have been converted")
+	if code, ok := c.parse(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 1 {
+		return c.fail(exitUsage, "no directory mentioned. use 'utfcoder watch -to encoding -o directory directory'")
+	}
+	w.dir = fs.Arg(0)
+
+	if err := w.b.cfg.resolve(fs); err != nil {
+		return c.fail(exitUsage, err)
+	}
+	if err := RunPrechecks(&w.b.cfg); err != nil {
+		return c.fail(exitUsage, err)
+	}
+	if len(w.b.outputDir) == 0 {
+		return c.fail(exitUsage, "no output directory provided. use '-o directory'")
+	}
+	if w.interval <= 0 {
+		return c.fail(exitUsage, "invalid interval provided. use '-interval 1s'")
+	}
+	// the converted files would be converted again
+	if isSameFile(w.dir, w.b.outputDir) {
+		return c.fail(exitUsage, "the output directory is the watched directory. use '-o' with another directory")
+	}
+	if len(w.statePath) == 0 {
+		w.statePath = filepath.Join(w.b.outputDir, ".utfcoder-watch.json")
+	}
+
+	if info, err := os.Stat(w.dir); err != nil || !info.IsDir() {
+		return c.fail(exitFailure, "cannot watch", w.dir+", it is not a directory")
+	}
+	if err := os.MkdirAll(w.b.outputDir, 0755); err != nil {
+		return c.fail(exitFailure, err)
+	}
+	if err := w.loadState(); err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var events <-chan struct{}
+	if !w.isPolling && !w.isOnce {
+		if n, err := newNotifier(w.dir); err != nil {
+			logger.Log("Polling", w.dir, "every", w.interval, "as inotify is not available:", err)
+		} else {
+			defer n.Close()
+			events = n.Events()
+			logger.Log("Watching", w.dir, "with inotify")
+		}
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	code := exitOK
+	for {
+		ready, isChanging, err := w.scan(time.Now())
+		if err != nil {
+			return c.fail(exitFailure, err)
+		}
+
+		hasFailed, err := w.convert(c, ready)
+		if err != nil {
+			return c.fail(exitFailure, err)
+		}
+		if hasFailed {
+			code = exitFailure
+		}
+
+		if w.isOnce && !isChanging {
+			return code
+		}
+
+		select {
+		case <-ctx.Done():
+			return code
+		case <-events:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// inotify wakes watch up as soon as a file in the directory is created, written, closed or moved into it
+type inotify struct {
+	file   *os.File
+	events chan struct{}
+}
+
+func newNotifier(dir string) (notifier, error) {
+	// a non-blocking descriptor is read through the runtime poller, so closing the file ends the pending read
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	mask := uint32(syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+
+	n := &inotify{file: os.NewFile(uintptr(fd), "inotify"), events: make(chan struct{}, 1)}
+	go n.read()
+	return n, nil
+}
+
+// turns the inotify events into wake ups, which file changed doesn't matter as the whole directory is scanned
+func (n *inotify) read() {
+	buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		if _, err := n.file.Read(buffer); err != nil {
+			return
+		}
+		select {
+		case n.events <- struct{}{}:
+		default:
+			// a wake up is already pending
+		}
+	}
+}
+
+func (n *inotify) Events() <-chan struct{} {
+	return n.events
+}
+
+func (n *inotify) Close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// there is no inotify, so watch polls
+func newNotifier(dir string) (notifier, error) {
+	return nil, errors.New("inotify is only available on linux")
+}
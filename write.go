@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// mode of written files which have no file to take it from, only the user may read them
+const defaultFileMode os.FileMode = 0600
+
+// writes output to path through a temporary file in the same directory, which is synced and renamed over path, so
+// path holds either its old or its new contents even when the process dies. the mode, owner and modification time
+// are taken from attributesFrom where possible, unless it is empty. a symbolic link at path is written through, the
+// file it points to gets the new contents and the link stays
+func writeFileAtomic(path string, output []byte, attributesFrom string) error {
+	if err := replaceFile(path, output, attributesFrom); err != nil {
+		return fmt.Errorf("cannot write %v: %w", path, err)
+	}
+	return nil
+}
+
+func replaceFile(path string, output []byte, attributesFrom string) error {
+	// renamed over, the link itself would be replaced by a regular file
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+		path = resolved
+	}
+
+	mode := defaultFileMode
+	var attributes os.FileInfo
+	if len(attributesFrom) != 0 {
+		info, err := os.Stat(attributesFrom)
+		if err != nil {
+			return err
+		}
+		attributes, mode = info, info.Mode().Perm()
+	} else if info, err := os.Stat(path); err == nil {
+		// a target which is written again keeps its mode
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".utfcoder-*")
+	if err != nil {
+		return err
+	}
+
+	// the temporary file is gone whatever happens, after the rename there is nothing left to remove
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(output); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	if attributes != nil {
+		// only root can give a file away, so the owner is kept where possible
+		if uid, gid, ok := fileOwner(attributes); ok {
+			_ = os.Chown(temp.Name(), uid, gid)
+		}
+		if err := os.Chtimes(temp.Name(), time.Time{}, attributes.ModTime()); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return err
+	}
+
+	// the rename itself is only durable once the directory is synced, which not every platform allows
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// returns whether both paths name the same existing file
+func isSameFile(path, otherPath string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	otherInfo, err := os.Stat(otherPath)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info, otherInfo)
+}